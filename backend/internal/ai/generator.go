@@ -10,15 +10,28 @@ import (
 
 // RebusPrompt represents a prompt for generating a rebus puzzle
 type RebusPrompt struct {
-	Prompt string `json:"prompt"` // Prompt for image generation
-	Answer string `json:"answer"` // Correct answer
-	Hint   string `json:"hint"`   // Hint for the puzzle
+	Prompt      string `json:"prompt"`      // Prompt for image generation
+	Answer      string `json:"answer"`      // Correct answer
+	Hint        string `json:"hint"`        // Hint for the puzzle
+	Explanation string `json:"explanation"` // How the wordplay leads to the answer
+	Theme       string `json:"theme"`       // Theme shared by the day's puzzles
+	Difficulty  string `json:"difficulty"`  // easy, medium or hard
+}
+
+// normalizeDifficulty lowercases a difficulty from Claude, defaulting to medium if unrecognized
+func normalizeDifficulty(difficulty string) string {
+	d := strings.ToLower(strings.TrimSpace(difficulty))
+	if !models.IsValidDifficulty(d) {
+		return models.DifficultyMedium
+	}
+	return d
 }
 
 // AIGenerator interface for generating rebus puzzles
 type AIGenerator interface {
 	GenerateRebusPuzzle(date string, index int, imageStore *store.Store) (*models.Puzzle, error)
 	GenerateRebusPuzzles(date string, imageStore *store.Store) ([]*models.Puzzle, error)
+	GenerateBankSet(setID string, imageStore *store.Store) ([]*models.BankPuzzle, error)
 }
 
 // RealAIGenerator implements AIGenerator using Claude API and image generation service
@@ -58,20 +71,25 @@ func (g *RealAIGenerator) GenerateRebusPuzzle(date string, index int, imageStore
 	}
 
 	// Save image locally
-	if err := imageStore.SaveImage(date, index, imageData); err != nil {
+	imageURL, imagePath, meta, err := imageStore.SaveImage(imageData)
+	if err != nil {
 		return nil, fmt.Errorf("failed to save image: %w", err)
 	}
 
 	// Create puzzle
 	puzzleID := fmt.Sprintf("%s-%d", date, index)
 	puzzle := &models.Puzzle{
-		ID:        puzzleID,
-		ImageURL:  imageStore.GetImageURL(date, index),
-		ImagePath: imageStore.GetImagePath(date, index),
-		Answer:    strings.ToLower(strings.TrimSpace(prompt.Answer)),
-		Hint:      prompt.Hint,
-		Date:      date,
-		Index:     index,
+		ID:          puzzleID,
+		ImageURL:    imageURL,
+		ImagePath:   imagePath,
+		ImageMeta:   meta,
+		Answer:      strings.ToLower(strings.TrimSpace(prompt.Answer)),
+		Hint:        prompt.Hint,
+		Explanation: prompt.Explanation,
+		Theme:       strings.TrimSpace(prompt.Theme),
+		Difficulty:  normalizeDifficulty(prompt.Difficulty),
+		Date:        date,
+		Index:       index,
 	}
 
 	return puzzle, nil
@@ -102,23 +120,69 @@ func (g *RealAIGenerator) GenerateRebusPuzzles(date string, imageStore *store.St
 		fmt.Printf("Successfully generated image %d/5\n", i+1)
 
 		// Save image locally
-		if err := imageStore.SaveImage(date, i, imageData); err != nil {
+		imageURL, imagePath, meta, err := imageStore.SaveImage(imageData)
+		if err != nil {
 			return nil, fmt.Errorf("failed to save image for puzzle %d: %w", i, err)
 		}
 
 		// Create puzzle
 		puzzleID := fmt.Sprintf("%s-%d", date, i)
 		puzzles[i] = &models.Puzzle{
-			ID:        puzzleID,
-			ImageURL:  imageStore.GetImageURL(date, i),
-			ImagePath: imageStore.GetImagePath(date, i),
-			Answer:    strings.ToLower(strings.TrimSpace(prompt.Answer)),
-			Hint:      prompt.Hint,
-			Date:      date,
-			Index:     i,
+			ID:          puzzleID,
+			ImageURL:    imageURL,
+			ImagePath:   imagePath,
+			ImageMeta:   meta,
+			Answer:      strings.ToLower(strings.TrimSpace(prompt.Answer)),
+			Hint:        prompt.Hint,
+			Explanation: prompt.Explanation,
+			Theme:       strings.TrimSpace(prompt.Theme),
+			Difficulty:  normalizeDifficulty(prompt.Difficulty),
+			Date:        date,
+			Index:       i,
 		}
 	}
 
 	fmt.Printf("Successfully generated all 5 rebus puzzles for date: %s\n", date)
 	return puzzles, nil
 }
+
+// GenerateBankSet generates a set of 5 reserve puzzles for the fallback bank
+// Images are stored by content, like any other, so the set can later be promoted to any date.
+func (g *RealAIGenerator) GenerateBankSet(setID string, imageStore *store.Store) ([]*models.BankPuzzle, error) {
+	fmt.Printf("Starting to generate reserve bank set: %s\n", setID)
+
+	prompts, err := g.promptGenerator.GetBankPromptsFromClaude(setID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompts from Claude: %w", err)
+	}
+
+	puzzles := make([]*models.BankPuzzle, len(prompts))
+	for i, prompt := range prompts {
+		imageData, err := g.imageGenerator.GenerateImageFromPrompt(prompt.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate image for bank puzzle %d: %w", i, err)
+		}
+
+		imageURL, imagePath, meta, err := imageStore.SaveImage(imageData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save image for bank puzzle %d: %w", i, err)
+		}
+
+		puzzles[i] = &models.BankPuzzle{
+			SetID:       setID,
+			Index:       i,
+			Prompt:      prompt.Prompt,
+			ImageURL:    imageURL,
+			ImagePath:   imagePath,
+			ImageMeta:   meta,
+			Answer:      strings.ToLower(strings.TrimSpace(prompt.Answer)),
+			Hint:        prompt.Hint,
+			Explanation: prompt.Explanation,
+			Theme:       strings.TrimSpace(prompt.Theme),
+			Difficulty:  normalizeDifficulty(prompt.Difficulty),
+		}
+	}
+
+	fmt.Printf("Successfully generated reserve bank set: %s\n", setID)
+	return puzzles, nil
+}
@@ -32,16 +32,27 @@ func NewPromptGenerator(claudeAPIKey string) *PromptGenerator {
 
 // GetPromptsFromClaude fetches 5 rebus puzzle prompts from Claude API
 func (pg *PromptGenerator) GetPromptsFromClaude(date string) ([]RebusPrompt, error) {
+	return pg.getPrompts(date, "date "+date)
+}
+
+// GetBankPromptsFromClaude fetches 5 rebus puzzle prompts for a reserve bank set
+func (pg *PromptGenerator) GetBankPromptsFromClaude(setID string) ([]RebusPrompt, error) {
+	return pg.getPrompts("bank:"+setID, "a reserve set that can be used on any day")
+}
+
+// getPrompts fetches 5 prompts from Claude API, caching them under cacheKey
+// occasion completes "Generate exactly 5 different rebus puzzle prompts for ..."
+func (pg *PromptGenerator) getPrompts(cacheKey, occasion string) ([]RebusPrompt, error) {
 	// Check cache first
 	pg.cacheMutex.Lock()
-	if prompts, exists := pg.promptCache[date]; exists {
+	if prompts, exists := pg.promptCache[cacheKey]; exists {
 		pg.cacheMutex.Unlock()
-		fmt.Printf("Using cached prompts for date: %s\n", date)
+		fmt.Printf("Using cached prompts for: %s\n", cacheKey)
 		return prompts, nil
 	}
 	pg.cacheMutex.Unlock()
 
-	fmt.Printf("Calling Claude API to generate 5 rebus puzzle prompts for date: %s\n", date)
+	fmt.Printf("Calling Claude API to generate 5 rebus puzzle prompts for: %s\n", occasion)
 
 	// Claude API endpoint
 	claudeURL := "https://api.anthropic.com/v1/messages"
@@ -60,13 +71,16 @@ Return the response as a JSON array with this exact format:
   {
     "prompt": "Detailed description of what the rebus puzzle image should show (describe visual elements clearly)",
     "answer": "the correct answer (common phrase or word)",
-    "hint": "a helpful hint that guides without giving away the answer"
+    "hint": "a helpful hint that guides without giving away the answer",
+    "explanation": "a short explanation of how the visual wordplay leads to the answer",
+    "theme": "the theme shared by all 5 puzzles (same value for each puzzle)",
+    "difficulty": "easy, medium or hard"
   },
   ... (4 more puzzles)
 ]`
 
 	// Enhanced user prompt with more specific instructions
-	userPrompt := fmt.Sprintf(`Generate exactly 5 different rebus puzzle prompts for date %s. 
+	userPrompt := fmt.Sprintf(`Generate exactly 5 different rebus puzzle prompts for %s. 
 
 For each puzzle, you must provide:
 
@@ -85,12 +99,18 @@ For each puzzle, you must provide:
 
 3. HINT: A helpful hint that guides the solver without revealing the answer directly. Make it encouraging and fun.
 
+4. EXPLANATION: One or two sentences explaining how the visual elements combine to form the answer. This is shown to players after the puzzle is over.
+
+5. THEME: Pick ONE short theme for the day (e.g., "food", "weather", "animals") and use it for all 5 puzzles.
+
+6. DIFFICULTY: Rate each puzzle as "easy", "medium" or "hard". Aim for a mix across the 5 puzzles.
+
 Requirements:
 - All 5 puzzles should be creative and varied
 - Use different types of rebus puzzles (word combinations, picture-word mixes, symbol arrangements)
 - Ensure answers are appropriate for all ages
 - Make sure the phrases are VERY COMMON and easily recognizable
-- The visual descriptions should be rich and detailed for better image generation`, date)
+- The visual descriptions should be rich and detailed for better image generation`, occasion)
 
 	requestPayload := map[string]interface{}{
 		"model":      "claude-sonnet-4-20250514",
@@ -188,9 +208,9 @@ Requirements:
 
 	// Cache the prompts
 	pg.cacheMutex.Lock()
-	pg.promptCache[date] = prompts
+	pg.promptCache[cacheKey] = prompts
 	pg.cacheMutex.Unlock()
-	fmt.Printf("Prompts cached for date: %s\n", date)
+	fmt.Printf("Prompts cached for: %s\n", cacheKey)
 
 	return prompts, nil
 }
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -19,6 +21,49 @@ type Config struct {
 	BatchJobHour    int    // Hour of day to run batch job (0-23)
 	BatchJobMinute  int    // Minute of hour to run batch job (0-59)
 	AllowedOrigins  []string
+	// Job Configuration (cron expressions are evaluated in PublicationTimezone)
+	GenerateJobSchedule   string // Defaults to BatchJobHour:BatchJobMinute daily
+	GenerateJobEnabled    bool
+	GenerationBufferDays  int // Keep puzzles generated for today + N future days
+	CleanupJobSchedule    string
+	CleanupJobEnabled     bool
+	ProgressRetentionDays int // Player progress older than this is deleted by the cleanup job
+	StatsJobSchedule      string
+	StatsJobEnabled       bool
+	RemindersJobSchedule  string
+	RemindersJobEnabled   bool
+	FallbackJobSchedule   string // Deadline by which today must have puzzles before a bank set is promoted
+	FallbackJobEnabled    bool
+	BankTopupJobSchedule  string
+	BankTopupJobEnabled   bool
+	BankTargetSets        int  // Reserve puzzle sets kept in the fallback bank
+	BankAutoApprove       bool // Approve generated bank sets without an admin review
+	PublishJobSchedule    string
+	PublishJobEnabled     bool
+	ImageGCJobSchedule    string
+	ImageGCJobEnabled     bool
+	ImageGCGraceHours     int    // Orphaned images modified more recently than this are left alone
+	ImageGCAction         string // "delete" or "quarantine" orphaned images
+	InstanceID            string // Identifies this replica as the holder of job locks
+	BackfillConcurrency   int    // Maximum dates a backfill generates at once
+	BackfillBudget        int    // Maximum dates a single backfill may generate (0 = unlimited)
+	// Gameplay Configuration
+	MaxAttemptsPerPuzzle int  // Maximum guesses per puzzle per player (0 = unlimited)
+	ReviewRequired       bool // Generated puzzles wait for an admin's approval before players see them
+	// Image Processing Configuration
+	ImageWidth     int // Stored puzzle images are scaled to fit and padded to this size
+	ImageHeight    int
+	ImageThreshold int // Luminance (1-255) that splits white foreground from black background; 0 keeps colours
+	// Rate Limiting Configuration
+	RateLimitBackend  string // "memory" (per replica) or "postgres" (shared by every replica)
+	RateLimitVerify   string // Rule for POST /api/puzzles/verify per player, e.g. "30/1m" ("off" disables)
+	RateLimitVerifyIP string // Rule for POST /api/puzzles/verify per client IP, applied on top of the per-player rule
+	RateLimitTrigger  string // Rule for POST /api/puzzles/trigger per API token
+	TrustProxyHeaders bool   // Take client IPs from X-Forwarded-For
+	// Release Configuration
+	PublicationTimezone string // IANA timezone that defines "today" for generation and releases
+	ReleaseHour         int    // Default hour of day (0-23) at which a date's puzzles are released
+	AdminAPIKey         string // Bootstrap token with the admin role, for creating the first API tokens
 	// Supabase S3 Configuration
 	SupabaseS3Bucket    string // S3 bucket name
 	SupabaseS3Region    string // S3 region
@@ -26,6 +71,10 @@ type Config struct {
 	SupabaseS3SecretKey string // S3 secret key
 	SupabaseS3Endpoint  string // S3 endpoint URL (Supabase storage endpoint)
 	SupabaseS3PublicURL string // Public URL base for accessing images
+	SupabaseImageMode   string // How /api/images serves images: "public", "proxy" or "redirect"
+	SupabasePresignTTL  int    // Minutes presigned image URLs stay valid in redirect mode
+	ImageProxyCacheMB   int    // Memory for caching images in proxy mode (0 disables the cache)
+	SupabasePrivate     bool   // Keep images private: no public-read ACL, non-guessable keys, served only once released
 }
 
 // Load loads configuration from environment variables with defaults
@@ -54,11 +103,9 @@ func Load() *Config {
 	}
 
 	// Default batch job time: 6:00 AM
-	batchHour := 6
-	batchMinute := 0
-
-	// You can override with environment variables if needed
-	// BATCH_JOB_HOUR and BATCH_JOB_MINUTE
+	// Override with BATCH_JOB_HOUR and BATCH_JOB_MINUTE, or JOB_GENERATE_SCHEDULE for a full cron expression
+	batchHour := getEnvInt("BATCH_JOB_HOUR", 6)
+	batchMinute := getEnvInt("BATCH_JOB_MINUTE", 0)
 
 	allowedOrigins := []string{
 		"http://localhost:5173",
@@ -97,6 +144,49 @@ func Load() *Config {
 		BatchJobHour:    batchHour,
 		BatchJobMinute:  batchMinute,
 		AllowedOrigins:  allowedOrigins,
+		// Job Configuration
+		GenerateJobSchedule:   getEnvString("JOB_GENERATE_SCHEDULE", fmt.Sprintf("%d %d * * *", batchMinute, batchHour)),
+		GenerateJobEnabled:    getEnvBool("JOB_GENERATE_ENABLED", true),
+		GenerationBufferDays:  getEnvInt("GENERATION_BUFFER_DAYS", 0),
+		CleanupJobSchedule:    getEnvString("JOB_CLEANUP_SCHEDULE", "30 3 * * *"),
+		CleanupJobEnabled:     getEnvBool("JOB_CLEANUP_ENABLED", true),
+		ProgressRetentionDays: getEnvInt("PROGRESS_RETENTION_DAYS", 90),
+		StatsJobSchedule:      getEnvString("JOB_STATS_SCHEDULE", "15 * * * *"),
+		StatsJobEnabled:       getEnvBool("JOB_STATS_ENABLED", true),
+		RemindersJobSchedule:  getEnvString("JOB_REMINDERS_SCHEDULE", "0 18 * * *"),
+		RemindersJobEnabled:   getEnvBool("JOB_REMINDERS_ENABLED", true),
+		FallbackJobSchedule:   getEnvString("JOB_FALLBACK_SCHEDULE", fmt.Sprintf("%d %d * * *", batchMinute, (batchHour+1)%24)),
+		FallbackJobEnabled:    getEnvBool("JOB_FALLBACK_ENABLED", true),
+		BankTopupJobSchedule:  getEnvString("JOB_BANK_TOPUP_SCHEDULE", "0 3 * * *"),
+		BankTopupJobEnabled:   getEnvBool("JOB_BANK_TOPUP_ENABLED", true),
+		BankTargetSets:        getEnvInt("BANK_TARGET_SETS", 3),
+		BankAutoApprove:       getEnvBool("BANK_AUTO_APPROVE", false),
+		PublishJobSchedule:    getEnvString("JOB_PUBLISH_SCHEDULE", "*/10 * * * *"),
+		PublishJobEnabled:     getEnvBool("JOB_PUBLISH_ENABLED", true),
+		ImageGCJobSchedule:    getEnvString("JOB_IMAGE_GC_SCHEDULE", "45 4 * * *"),
+		ImageGCJobEnabled:     getEnvBool("JOB_IMAGE_GC_ENABLED", true),
+		ImageGCGraceHours:     getEnvInt("IMAGE_GC_GRACE_HOURS", 72),
+		ImageGCAction:         getEnvString("IMAGE_GC_ACTION", "quarantine"),
+		InstanceID:            getEnvString("INSTANCE_ID", defaultInstanceID()),
+		BackfillConcurrency:   getEnvInt("BACKFILL_CONCURRENCY", 2),
+		BackfillBudget:        getEnvInt("BACKFILL_BUDGET", 31),
+		// Gameplay Configuration
+		MaxAttemptsPerPuzzle: getEnvInt("MAX_ATTEMPTS_PER_PUZZLE", 5),
+		ReviewRequired:       getEnvBool("REVIEW_REQUIRED", true),
+		// Image Processing Configuration
+		ImageWidth:     getEnvInt("IMAGE_WIDTH", 800),
+		ImageHeight:    getEnvInt("IMAGE_HEIGHT", 600),
+		ImageThreshold: getEnvInt("IMAGE_THRESHOLD", 128),
+		// Rate Limiting Configuration
+		RateLimitBackend:  getEnvString("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitVerify:   getEnvString("RATE_LIMIT_VERIFY", "30/1m"),
+		RateLimitVerifyIP: getEnvString("RATE_LIMIT_VERIFY_IP", "120/1m"),
+		RateLimitTrigger:  getEnvString("RATE_LIMIT_TRIGGER", "5/1h"),
+		TrustProxyHeaders: getEnvBool("TRUST_PROXY_HEADERS", false),
+		// Release Configuration
+		PublicationTimezone: getEnvString("PUBLICATION_TIMEZONE", "UTC"),
+		ReleaseHour:         getEnvInt("RELEASE_HOUR", 0),
+		AdminAPIKey:         os.Getenv("ADMIN_API_KEY"),
 		// Supabase S3 Configuration
 		SupabaseS3Bucket:    os.Getenv("SUPABASE_S3_BUCKET"),
 		SupabaseS3Region:    os.Getenv("SUPABASE_S3_REGION"),
@@ -104,5 +194,52 @@ func Load() *Config {
 		SupabaseS3SecretKey: os.Getenv("SUPABASE_S3_SECRET_KEY"),
 		SupabaseS3Endpoint:  os.Getenv("SUPABASE_S3_ENDPOINT"),
 		SupabaseS3PublicURL: os.Getenv("SUPABASE_S3_PUBLIC_URL"),
+		SupabaseImageMode:   getEnvString("SUPABASE_IMAGE_MODE", "public"),
+		SupabasePresignTTL:  getEnvInt("SUPABASE_PRESIGN_TTL_MINUTES", 15),
+		ImageProxyCacheMB:   getEnvInt("IMAGE_PROXY_CACHE_MB", 64),
+		SupabasePrivate:     getEnvBool("SUPABASE_PRIVATE_BUCKET", false),
+	}
+}
+
+// defaultInstanceID identifies this process as hostname:pid
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// getEnvInt reads an integer environment variable, falling back to def when unset or invalid
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return def
+	}
+	return parsed
+}
+
+// getEnvString reads a string environment variable, falling back to def when unset
+func getEnvString(key, def string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvBool reads a boolean environment variable, falling back to def when unset or invalid
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return def
 	}
+	return parsed
 }
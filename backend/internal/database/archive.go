@@ -0,0 +1,81 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"backend/internal/models"
+)
+
+// ListArchiveDays returns per-day summaries of dates that have puzzles, newest first
+// Filters are applied per day, so a theme or difficulty match keeps the whole day's summary intact.
+func (db *DB) ListArchiveDays(filter models.ArchiveFilter) ([]models.ArchiveDay, error) {
+	query := `
+		SELECT
+			date,
+			COUNT(*),
+			(array_agg(theme ORDER BY index_num))[1],
+			SUM(CASE WHEN difficulty = $7 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN difficulty = $8 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN difficulty = $9 THEN 1 ELSE 0 END),
+			array_agg(image_url ORDER BY index_num),
+			array_agg(image_variants @> $11 ORDER BY index_num)
+		FROM puzzles
+		WHERE ($1 = '' OR date <= $1)
+			AND ($2 = '' OR date < $2)
+			AND ($3 = '' OR date LIKE $3 || '-%')
+			AND status = ANY($10)
+		GROUP BY date
+		HAVING ($4 = '' OR bool_or(LOWER(theme) = LOWER($4)))
+			AND ($5 = '' OR bool_or(difficulty = $5))
+		ORDER BY date DESC
+		LIMIT $6
+	`
+
+	rows, err := db.Query(query,
+		filter.Through,
+		filter.Before,
+		filter.Month,
+		filter.Theme,
+		filter.Difficulty,
+		filter.Limit,
+		models.DifficultyEasy,
+		models.DifficultyMedium,
+		models.DifficultyHard,
+		pq.Array(playableStatuses),
+		`[{"name": "`+models.VariantThumb+`"}]`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archive: %w", err)
+	}
+	defer rows.Close()
+
+	days := []models.ArchiveDay{}
+	for rows.Next() {
+		var day models.ArchiveDay
+		var easy, medium, hard int
+		var hasThumb []bool
+		if err := rows.Scan(&day.Date, &day.Count, &day.Theme, &easy, &medium, &hard, pq.Array(&day.Thumbnails), pq.Array(&hasThumb)); err != nil {
+			return nil, fmt.Errorf("failed to scan archive day: %w", err)
+		}
+		// Use the thumbnail variant where the image has one
+		for i := range day.Thumbnails {
+			if i < len(hasThumb) && hasThumb[i] {
+				day.Thumbnails[i] = models.ImageVariantPath(day.Thumbnails[i], models.VariantThumb, "png")
+			}
+		}
+		day.Difficulties = map[string]int{
+			models.DifficultyEasy:   easy,
+			models.DifficultyMedium: medium,
+			models.DifficultyHard:   hard,
+		}
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archive: %w", err)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,316 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+// bankColumns is the column list read by scanBankPuzzle, in order
+const bankColumns = `id, set_id, index_num, prompt, answer, hint, explanation, theme, difficulty,
+	image_url, image_path, approved_at, COALESCE(used_on, ''), created_at, image_variants,
+	blur_hash, dominant_color, image_sha256, image_size`
+
+// scanBankPuzzle scans a row selected with bankColumns
+func scanBankPuzzle(row rowScanner) (models.BankPuzzle, error) {
+	var p models.BankPuzzle
+	var approvedAt sql.NullTime
+	var variants []byte
+	err := row.Scan(
+		&p.ID,
+		&p.SetID,
+		&p.Index,
+		&p.Prompt,
+		&p.Answer,
+		&p.Hint,
+		&p.Explanation,
+		&p.Theme,
+		&p.Difficulty,
+		&p.ImageURL,
+		&p.ImagePath,
+		&approvedAt,
+		&p.UsedOn,
+		&p.CreatedAt,
+		&variants,
+		&p.BlurHash,
+		&p.DominantColor,
+		&p.ImageSHA256,
+		&p.ImageSize,
+	)
+	if err != nil {
+		return p, err
+	}
+	if approvedAt.Valid {
+		p.Approved = true
+		p.ApprovedAt = &approvedAt.Time
+	}
+	return p, parseImageVariants(variants, &p.ImageVariants)
+}
+
+// SaveBankSet stores a new set of bank puzzles
+// The set is approved straight away when approved is true, otherwise it waits for ApproveBankSet.
+func (db *DB) SaveBankSet(puzzles []models.BankPuzzle, approved bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO puzzle_bank (set_id, index_num, prompt, answer, hint, explanation, theme, difficulty,
+			image_url, image_path, approved_at, created_at, image_variants, blur_hash, dominant_color, image_sha256, image_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`
+
+	stmt, err := tx.Prepare(insertQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	var approvedAt *time.Time
+	if approved {
+		approvedAt = &now
+	}
+
+	for _, p := range puzzles {
+		_, err := stmt.Exec(
+			p.SetID,
+			p.Index,
+			p.Prompt,
+			p.Answer,
+			p.Hint,
+			p.Explanation,
+			p.Theme,
+			p.Difficulty,
+			p.ImageURL,
+			p.ImagePath,
+			approvedAt,
+			now,
+			imageVariantsJSON(p.ImageVariants),
+			p.BlurHash,
+			p.DominantColor,
+			p.ImageSHA256,
+			p.ImageSize,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert bank puzzle %s/%d: %w", p.SetID, p.Index, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CountReserveBankSets counts sets that haven't been promoted yet, including ones awaiting approval
+func (db *DB) CountReserveBankSets() (int, error) {
+	query := `SELECT COUNT(DISTINCT set_id) FROM puzzle_bank WHERE used_on IS NULL`
+
+	var count int
+	if err := db.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count bank sets: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListBankSets lists bank sets, unused sets first and oldest first within each group
+func (db *DB) ListBankSets(includeUsed bool) ([]models.BankSet, error) {
+	query := `SELECT ` + bankColumns + ` FROM puzzle_bank`
+	if !includeUsed {
+		query += ` WHERE used_on IS NULL`
+	}
+	query += ` ORDER BY used_on DESC NULLS FIRST, created_at, set_id, index_num`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bank sets: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []models.BankSet
+	for rows.Next() {
+		p, err := scanBankPuzzle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bank puzzle: %w", err)
+		}
+
+		if len(sets) == 0 || sets[len(sets)-1].SetID != p.SetID {
+			sets = append(sets, models.BankSet{
+				SetID:    p.SetID,
+				Theme:    p.Theme,
+				Approved: p.Approved,
+				UsedOn:   p.UsedOn,
+			})
+		}
+		set := &sets[len(sets)-1]
+		set.Puzzles = append(set.Puzzles, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bank puzzles: %w", err)
+	}
+
+	return sets, nil
+}
+
+// ApproveBankSet approves a reserve set so it can be promoted
+// Returns false if no unused set has that ID.
+func (db *DB) ApproveBankSet(setID string) (bool, error) {
+	query := `
+		UPDATE puzzle_bank
+		SET approved_at = COALESCE(approved_at, $2)
+		WHERE set_id = $1 AND used_on IS NULL
+	`
+
+	result, err := db.Exec(query, setID, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to approve bank set: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to approve bank set: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// ClaimBankSet marks the oldest approved reserve set as used on a date and returns its puzzles
+// Returns nil if the bank has no approved sets left. Concurrent claims never get the same set.
+func (db *DB) ClaimBankSet(date string) ([]models.BankPuzzle, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	selectQuery := `
+		SELECT set_id FROM puzzle_bank
+		WHERE used_on IS NULL AND approved_at IS NOT NULL
+		ORDER BY created_at, set_id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	var setID string
+	err = tx.QueryRow(selectQuery).Scan(&setID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find bank set: %w", err)
+	}
+
+	updateQuery := `
+		UPDATE puzzle_bank SET used_on = $2
+		WHERE set_id = $1
+		RETURNING ` + bankColumns
+
+	rows, err := tx.Query(updateQuery, setID, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim bank set: %w", err)
+	}
+	defer rows.Close()
+
+	var puzzles []models.BankPuzzle
+	for rows.Next() {
+		p, err := scanBankPuzzle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bank puzzle: %w", err)
+		}
+		puzzles = append(puzzles, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bank puzzles: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return puzzles, nil
+}
+
+// UnclaimBankSet returns a claimed set to the reserve, e.g. when promoting it failed
+func (db *DB) UnclaimBankSet(setID string) error {
+	query := `UPDATE puzzle_bank SET used_on = NULL WHERE set_id = $1`
+
+	if _, err := db.Exec(query, setID); err != nil {
+		return fmt.Errorf("failed to unclaim bank set: %w", err)
+	}
+
+	return nil
+}
+
+// ListImagePaths returns the image_path of every puzzle, bank entry and kept puzzle image version
+func (db *DB) ListImagePaths() ([]string, error) {
+	query := `
+		SELECT image_path FROM puzzles
+		UNION
+		SELECT image_path FROM puzzle_bank
+		UNION
+		SELECT image_path FROM puzzle_image_versions
+		ORDER BY image_path
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image paths: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan image path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating image paths: %w", err)
+	}
+
+	return paths, nil
+}
+
+// RepointImage moves every puzzle, bank and image version row using an image to a copy of it, in one transaction
+func (db *DB) RepointImage(oldPath, newPath, newURL string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var updated int64
+	for _, query := range []string{
+		`UPDATE puzzles SET image_path = $2, image_url = $3, updated_at = CURRENT_TIMESTAMP WHERE image_path = $1`,
+		`UPDATE puzzle_bank SET image_path = $2, image_url = $3 WHERE image_path = $1`,
+		`UPDATE puzzle_image_versions SET image_path = $2, image_url = $3 WHERE image_path = $1`,
+	} {
+		result, err := tx.Exec(query, oldPath, newPath, newURL)
+		if err != nil {
+			return 0, fmt.Errorf("failed to repoint image: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to repoint image: %w", err)
+		}
+		updated += rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updated, nil
+}
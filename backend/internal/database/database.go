@@ -2,12 +2,13 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"backend/internal/models"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB wraps the database connection
@@ -54,6 +55,132 @@ func (db *DB) InitSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_puzzles_date ON puzzles(date);
 	CREATE INDEX IF NOT EXISTS idx_puzzles_id ON puzzles(id);
+	CREATE INDEX IF NOT EXISTS idx_puzzles_image_path ON puzzles(image_path);
+
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS explanation TEXT NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS theme VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS difficulty VARCHAR(20) NOT NULL DEFAULT 'medium';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS review_notes TEXT NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS alternate_answers TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS image_variants JSONB NOT NULL DEFAULT '[]';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS blur_hash VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS dominant_color VARCHAR(7) NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS image_sha256 VARCHAR(64) NOT NULL DEFAULT '';
+	ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS image_size BIGINT NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS idx_puzzles_status ON puzzles(status);
+
+	CREATE TABLE IF NOT EXISTS player_progress (
+		player_id VARCHAR(64) NOT NULL,
+		puzzle_id VARCHAR(50) NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		status VARCHAR(20) NOT NULL DEFAULT 'in_progress',
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (player_id, puzzle_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_player_progress_puzzle ON player_progress(puzzle_id);
+
+	CREATE TABLE IF NOT EXISTS puzzle_stats (
+		puzzle_id VARCHAR(50) PRIMARY KEY,
+		date VARCHAR(10) NOT NULL,
+		players INTEGER NOT NULL DEFAULT 0,
+		solved INTEGER NOT NULL DEFAULT 0,
+		failed INTEGER NOT NULL DEFAULT 0,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_puzzle_stats_date ON puzzle_stats(date);
+
+	CREATE TABLE IF NOT EXISTS puzzle_audit (
+		id BIGSERIAL PRIMARY KEY,
+		puzzle_id VARCHAR(50) NOT NULL,
+		action VARCHAR(30) NOT NULL,
+		actor VARCHAR(100) NOT NULL DEFAULT '',
+		before JSONB,
+		after JSONB,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_puzzle_audit_puzzle ON puzzle_audit(puzzle_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		role VARCHAR(20) NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		created_by VARCHAR(100) NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMPTZ,
+		revoked_at TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS rate_limits (
+		key VARCHAR(255) PRIMARY KEY,
+		tokens DOUBLE PRECISION NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS jobs (
+		name VARCHAR(100) PRIMARY KEY,
+		lock_holder VARCHAR(255),
+		locked_at TIMESTAMPTZ,
+		last_holder VARCHAR(255),
+		last_finished_at TIMESTAMPTZ,
+		last_status VARCHAR(20),
+		last_error TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS puzzle_releases (
+		date VARCHAR(10) PRIMARY KEY,
+		release_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS puzzle_bank (
+		id SERIAL PRIMARY KEY,
+		set_id VARCHAR(32) NOT NULL,
+		index_num INTEGER NOT NULL,
+		prompt TEXT NOT NULL DEFAULT '',
+		answer TEXT NOT NULL,
+		hint TEXT NOT NULL,
+		explanation TEXT NOT NULL DEFAULT '',
+		theme VARCHAR(100) NOT NULL DEFAULT '',
+		difficulty VARCHAR(20) NOT NULL DEFAULT 'medium',
+		image_url TEXT NOT NULL,
+		image_path TEXT NOT NULL,
+		approved_at TIMESTAMPTZ,
+		used_on VARCHAR(10),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(set_id, index_num)
+	);
+
+	ALTER TABLE puzzle_bank ADD COLUMN IF NOT EXISTS image_variants JSONB NOT NULL DEFAULT '[]';
+	ALTER TABLE puzzle_bank ADD COLUMN IF NOT EXISTS blur_hash VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE puzzle_bank ADD COLUMN IF NOT EXISTS dominant_color VARCHAR(7) NOT NULL DEFAULT '';
+	ALTER TABLE puzzle_bank ADD COLUMN IF NOT EXISTS image_sha256 VARCHAR(64) NOT NULL DEFAULT '';
+	ALTER TABLE puzzle_bank ADD COLUMN IF NOT EXISTS image_size BIGINT NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS idx_puzzle_bank_reserve ON puzzle_bank(created_at) WHERE used_on IS NULL;
+
+	CREATE TABLE IF NOT EXISTS puzzle_image_versions (
+		id SERIAL PRIMARY KEY,
+		puzzle_id VARCHAR(50) NOT NULL,
+		image_url TEXT NOT NULL,
+		image_path TEXT NOT NULL,
+		image_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+		image_size BIGINT NOT NULL DEFAULT 0,
+		image_variants JSONB NOT NULL DEFAULT '[]',
+		blur_hash VARCHAR(100) NOT NULL DEFAULT '',
+		dominant_color VARCHAR(7) NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(puzzle_id, image_path)
+	);
 	`
 
 	_, err := db.Exec(query)
@@ -63,7 +190,7 @@ func (db *DB) InitSchema() error {
 // GetPuzzlesForDate retrieves all puzzles for a specific date
 func (db *DB) GetPuzzlesForDate(date string) ([]models.Puzzle, error) {
 	query := `
-		SELECT id, date, index_num, image_url, image_path, answer, hint
+		SELECT ` + puzzleColumns + `
 		FROM puzzles
 		WHERE date = $1
 		ORDER BY index_num ASC
@@ -77,13 +204,11 @@ func (db *DB) GetPuzzlesForDate(date string) ([]models.Puzzle, error) {
 
 	var puzzles []models.Puzzle
 	for rows.Next() {
-		var p models.Puzzle
-		var indexNum int
-		if err := rows.Scan(&p.ID, &p.Date, &indexNum, &p.ImageURL, &p.ImagePath, &p.Answer, &p.Hint); err != nil {
+		p, err := scanPuzzle(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan puzzle: %w", err)
 		}
-		p.Index = indexNum
-		puzzles = append(puzzles, p)
+		puzzles = append(puzzles, *p)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -96,14 +221,26 @@ func (db *DB) GetPuzzlesForDate(date string) ([]models.Puzzle, error) {
 // SavePuzzle saves a single puzzle to the database
 func (db *DB) SavePuzzle(puzzle *models.Puzzle) error {
 	query := `
-		INSERT INTO puzzles (id, date, index_num, image_url, image_path, answer, hint, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO puzzles (id, date, index_num, image_url, image_path, answer, hint, explanation, theme, difficulty, created_at, status, alternate_answers, image_variants,
+			blur_hash, dominant_color, image_sha256, image_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		ON CONFLICT (id) 
 		DO UPDATE SET 
 			image_url = EXCLUDED.image_url,
 			image_path = EXCLUDED.image_path,
 			answer = EXCLUDED.answer,
-			hint = EXCLUDED.hint
+			hint = EXCLUDED.hint,
+			explanation = EXCLUDED.explanation,
+			theme = EXCLUDED.theme,
+			difficulty = EXCLUDED.difficulty,
+			status = EXCLUDED.status,
+			alternate_answers = EXCLUDED.alternate_answers,
+			image_variants = EXCLUDED.image_variants,
+			blur_hash = EXCLUDED.blur_hash,
+			dominant_color = EXCLUDED.dominant_color,
+			image_sha256 = EXCLUDED.image_sha256,
+			image_size = EXCLUDED.image_size,
+			updated_at = CURRENT_TIMESTAMP
 	`
 
 	_, err := db.Exec(query,
@@ -114,17 +251,33 @@ func (db *DB) SavePuzzle(puzzle *models.Puzzle) error {
 		puzzle.ImagePath,
 		puzzle.Answer,
 		puzzle.Hint,
+		puzzle.Explanation,
+		puzzle.Theme,
+		puzzle.Difficulty,
 		time.Now(),
+		initialStatus(puzzle),
+		pq.Array(alternateAnswers(puzzle)),
+		imageVariantsJSON(puzzle.ImageVariants),
+		puzzle.BlurHash,
+		puzzle.DominantColor,
+		puzzle.ImageSHA256,
+		puzzle.ImageSize,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to save puzzle: %w", err)
 	}
 
+	if err := recordImageVersion(db, puzzle); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // SavePuzzles saves multiple puzzles for a date (transactional)
+// The date's existing puzzles are replaced, apart from hand-made drafts; puzzles whose index a draft
+// uses are moved to the lowest free indexes, with IDs to match.
 func (db *DB) SavePuzzles(date string, puzzles []models.Puzzle) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -132,16 +285,25 @@ func (db *DB) SavePuzzles(date string, puzzles []models.Puzzle) error {
 	}
 	defer tx.Rollback()
 
+	drafts, err := lockDraftIndexes(tx, date)
+	if err != nil {
+		return err
+	}
+	if len(drafts) > 0 {
+		puzzles = placeAroundDrafts(date, puzzles, drafts)
+	}
+
 	// Delete existing puzzles for this date
-	deleteQuery := `DELETE FROM puzzles WHERE date = $1`
-	if _, err := tx.Exec(deleteQuery, date); err != nil {
+	deleteQuery := `DELETE FROM puzzles WHERE date = $1 AND status <> $2`
+	if _, err := tx.Exec(deleteQuery, date, models.PuzzleDraft); err != nil {
 		return fmt.Errorf("failed to delete existing puzzles: %w", err)
 	}
 
 	// Insert new puzzles
 	insertQuery := `
-		INSERT INTO puzzles (id, date, index_num, image_url, image_path, answer, hint, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO puzzles (id, date, index_num, image_url, image_path, answer, hint, explanation, theme, difficulty, created_at, status, alternate_answers, image_variants,
+			blur_hash, dominant_color, image_sha256, image_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 	`
 
 	stmt, err := tx.Prepare(insertQuery)
@@ -159,11 +321,24 @@ func (db *DB) SavePuzzles(date string, puzzles []models.Puzzle) error {
 			puzzle.ImagePath,
 			puzzle.Answer,
 			puzzle.Hint,
+			puzzle.Explanation,
+			puzzle.Theme,
+			puzzle.Difficulty,
 			time.Now(),
+			initialStatus(&puzzle),
+			pq.Array(alternateAnswers(&puzzle)),
+			imageVariantsJSON(puzzle.ImageVariants),
+			puzzle.BlurHash,
+			puzzle.DominantColor,
+			puzzle.ImageSHA256,
+			puzzle.ImageSize,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert puzzle %s: %w", puzzle.ID, err)
 		}
+		if err := recordImageVersion(tx, &puzzle); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -173,28 +348,113 @@ func (db *DB) SavePuzzles(date string, puzzles []models.Puzzle) error {
 	return nil
 }
 
-// HasPuzzlesForDate checks if puzzles exist for a date
+// lockDraftIndexes locks a date's draft puzzles and returns the indexes they use
+func lockDraftIndexes(tx *sql.Tx, date string) (map[int]bool, error) {
+	rows, err := tx.Query(`SELECT index_num FROM puzzles WHERE date = $1 AND status = $2 FOR UPDATE`, date, models.PuzzleDraft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drafts: %w", err)
+	}
+	defer rows.Close()
+
+	drafts := make(map[int]bool)
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		drafts[index] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating drafts: %w", err)
+	}
+
+	return drafts, nil
+}
+
+// placeAroundDrafts returns a copy of puzzles with any index a draft uses moved to the lowest free one
+func placeAroundDrafts(date string, puzzles []models.Puzzle, drafts map[int]bool) []models.Puzzle {
+	taken := make(map[int]bool, len(drafts)+len(puzzles))
+	for index := range drafts {
+		taken[index] = true
+	}
+	for _, p := range puzzles {
+		if !drafts[p.Index] {
+			taken[p.Index] = true
+		}
+	}
+
+	placed := make([]models.Puzzle, len(puzzles))
+	free := 0
+	for i, p := range puzzles {
+		if drafts[p.Index] {
+			for taken[free] {
+				free++
+			}
+			taken[free] = true
+			p.Index = free
+			p.ID = fmt.Sprintf("%s-%d", date, free)
+		}
+		placed[i] = p
+	}
+	return placed
+}
+
+// uncoveredStatuses don't cover a day: rejected puzzles, and hand-made drafts that generation works around
+var uncoveredStatuses = []string{models.PuzzleRejected, models.PuzzleDraft}
+
+// HasPuzzlesForDate checks if puzzles that aren't rejected or drafts exist for a date
+// Rejected puzzles and hand-made drafts don't cover a day, so a day with nothing else is generated.
 func (db *DB) HasPuzzlesForDate(date string) (bool, error) {
-	query := `SELECT COUNT(*) FROM puzzles WHERE date = $1`
+	query := `SELECT COUNT(*) FROM puzzles WHERE date = $1 AND status <> ALL($2)`
 	var count int
-	err := db.QueryRow(query, date).Scan(&count)
+	err := db.QueryRow(query, date, pq.Array(uncoveredStatuses)).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check puzzles: %w", err)
 	}
 	return count > 0, nil
 }
 
+// ListPuzzleDates returns the distinct dates between from and to (inclusive) that have puzzles that aren't rejected or drafts
+func (db *DB) ListPuzzleDates(from, to string) ([]string, error) {
+	query := `
+		SELECT DISTINCT date
+		FROM puzzles
+		WHERE date >= $1 AND date <= $2 AND status <> ALL($3)
+		ORDER BY date ASC
+	`
+
+	rows, err := db.Query(query, from, to, pq.Array(uncoveredStatuses))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query puzzle dates: %w", err)
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan puzzle date: %w", err)
+		}
+		dates = append(dates, date)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating puzzle dates: %w", err)
+	}
+
+	return dates, nil
+}
+
 // GetPuzzleByID retrieves a puzzle by its ID
 func (db *DB) GetPuzzleByID(id string) (*models.Puzzle, error) {
 	query := `
-		SELECT id, date, index_num, image_url, image_path, answer, hint
+		SELECT ` + puzzleColumns + `
 		FROM puzzles
 		WHERE id = $1
 	`
 
-	var p models.Puzzle
-	var indexNum int
-	err := db.QueryRow(query, id).Scan(&p.ID, &p.Date, &indexNum, &p.ImageURL, &p.ImagePath, &p.Answer, &p.Hint)
+	p, err := scanPuzzle(db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("puzzle not found: %s", id)
 	}
@@ -202,6 +462,78 @@ func (db *DB) GetPuzzleByID(id string) (*models.Puzzle, error) {
 		return nil, fmt.Errorf("failed to get puzzle: %w", err)
 	}
 
+	return p, nil
+}
+
+// puzzleColumns is the column list read by scanPuzzle
+const puzzleColumns = `id, date, index_num, image_url, image_path, answer, hint, explanation, theme, difficulty,
+	status, review_notes, reviewed_by, reviewed_at, alternate_answers, image_variants, blur_hash, dominant_color, updated_at,
+	image_sha256, image_size`
+
+// playableStatuses are the review statuses players may see
+var playableStatuses = []string{models.PuzzleApproved, models.PuzzlePublished}
+
+// initialStatus returns the status a puzzle is saved with, approved unless set
+func initialStatus(p *models.Puzzle) string {
+	if p.Status == "" {
+		return models.PuzzleApproved
+	}
+	return p.Status
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// alternateAnswers returns a puzzle's alternates, never nil since the column is NOT NULL
+func alternateAnswers(p *models.Puzzle) []string {
+	if p.AlternateAnswers == nil {
+		return []string{}
+	}
+	return p.AlternateAnswers
+}
+
+// imageVariantsJSON encodes image variants for their JSONB column
+func imageVariantsJSON(variants []models.ImageVariant) string {
+	if len(variants) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return "[]" // Can't happen: the struct has only strings and ints
+	}
+	return string(data)
+}
+
+// parseImageVariants decodes an image_variants column, leaving dst nil for images without variants
+func parseImageVariants(data []byte, dst *[]models.ImageVariant) error {
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("failed to decode image variants: %w", err)
+	}
+	if len(*dst) == 0 {
+		*dst = nil
+	}
+	return nil
+}
+
+// scanPuzzle scans a row selected with puzzleColumns into a Puzzle
+func scanPuzzle(row rowScanner) (*models.Puzzle, error) {
+	var p models.Puzzle
+	var indexNum int
+	var reviewedAt sql.NullTime
+	var variants []byte
+	if err := row.Scan(&p.ID, &p.Date, &indexNum, &p.ImageURL, &p.ImagePath, &p.Answer, &p.Hint, &p.Explanation, &p.Theme, &p.Difficulty,
+		&p.Status, &p.ReviewNotes, &p.ReviewedBy, &reviewedAt, pq.Array(&p.AlternateAnswers), &variants,
+		&p.BlurHash, &p.DominantColor, &p.UpdatedAt, &p.ImageSHA256, &p.ImageSize); err != nil {
+		return nil, err
+	}
+	if err := parseImageVariants(variants, &p.ImageVariants); err != nil {
+		return nil, err
+	}
 	p.Index = indexNum
+	if reviewedAt.Valid {
+		p.ReviewedAt = &reviewedAt.Time
+	}
 	return &p, nil
 }
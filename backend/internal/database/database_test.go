@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestPlaceAroundDrafts(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []int
+		drafts  []int
+		want    []int
+	}{
+		{"no clash", []int{0, 1, 2}, []int{5}, []int{0, 1, 2}},
+		{"first index", []int{0, 1, 2}, []int{0}, []int{3, 1, 2}},
+		{"gap before", []int{1, 2, 3}, []int{2}, []int{1, 0, 3}},
+		{"several", []int{0, 1, 2, 3, 4}, []int{1, 3, 6}, []int{0, 5, 2, 7, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			puzzles := make([]models.Puzzle, len(tt.indexes))
+			for i, index := range tt.indexes {
+				puzzles[i] = models.Puzzle{ID: fmt.Sprintf("2025-01-15-%d", index), Index: index}
+			}
+			drafts := make(map[int]bool)
+			for _, index := range tt.drafts {
+				drafts[index] = true
+			}
+
+			placed := placeAroundDrafts("2025-01-15", puzzles, drafts)
+
+			got := make([]int, len(placed))
+			for i, p := range placed {
+				got[i] = p.Index
+				if want := fmt.Sprintf("2025-01-15-%d", p.Index); p.ID != want {
+					t.Errorf("puzzle at index %d has ID %q, want %q", p.Index, p.ID, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("indexes = %v, want %v", got, tt.want)
+			}
+			for i, index := range tt.indexes {
+				if puzzles[i].Index != index {
+					t.Errorf("input puzzle %d was modified", i)
+				}
+			}
+		})
+	}
+}
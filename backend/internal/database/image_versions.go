@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"backend/internal/models"
+)
+
+// ErrImageVersionNotFound is returned when a puzzle has no image version with the requested ID
+var ErrImageVersionNotFound = errors.New("image version not found")
+
+// execer is satisfied by both *DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordImageVersion remembers the image a puzzle uses so it can be rolled back to later
+// An image the puzzle has used before keeps its original version.
+func recordImageVersion(db execer, p *models.Puzzle) error {
+	if p.ImagePath == "" {
+		return nil
+	}
+
+	query := `
+		INSERT INTO puzzle_image_versions (puzzle_id, image_url, image_path, image_sha256, image_size, image_variants,
+			blur_hash, dominant_color)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (puzzle_id, image_path) DO NOTHING
+	`
+	_, err := db.Exec(query,
+		p.ID,
+		p.ImageURL,
+		p.ImagePath,
+		p.ImageSHA256,
+		p.ImageSize,
+		imageVariantsJSON(p.ImageVariants),
+		p.BlurHash,
+		p.DominantColor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record image version: %w", err)
+	}
+
+	return nil
+}
+
+// ListPuzzleImageVersions returns every image a puzzle has used, newest first
+func (db *DB) ListPuzzleImageVersions(puzzleID string) ([]models.PuzzleImageVersion, error) {
+	query := `
+		SELECT v.id, v.puzzle_id, v.image_url, v.image_path, v.image_sha256, v.image_size, v.image_variants,
+			v.blur_hash, v.dominant_color, v.created_at, COALESCE(p.image_path = v.image_path, FALSE)
+		FROM puzzle_image_versions v
+		LEFT JOIN puzzles p ON p.id = v.puzzle_id
+		WHERE v.puzzle_id = $1
+		ORDER BY v.created_at DESC, v.id DESC
+	`
+
+	rows, err := db.Query(query, puzzleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []models.PuzzleImageVersion{}
+	for rows.Next() {
+		v, err := scanImageVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan image version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating image versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetPuzzleImageVersion returns one of a puzzle's image versions, or ErrImageVersionNotFound
+func (db *DB) GetPuzzleImageVersion(puzzleID string, id int64) (*models.PuzzleImageVersion, error) {
+	query := `
+		SELECT v.id, v.puzzle_id, v.image_url, v.image_path, v.image_sha256, v.image_size, v.image_variants,
+			v.blur_hash, v.dominant_color, v.created_at, COALESCE(p.image_path = v.image_path, FALSE)
+		FROM puzzle_image_versions v
+		LEFT JOIN puzzles p ON p.id = v.puzzle_id
+		WHERE v.puzzle_id = $1 AND v.id = $2
+	`
+
+	v, err := scanImageVersion(db.QueryRow(query, puzzleID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrImageVersionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image version: %w", err)
+	}
+
+	return &v, nil
+}
+
+// scanImageVersion reads a row selected by ListPuzzleImageVersions or GetPuzzleImageVersion
+func scanImageVersion(row rowScanner) (models.PuzzleImageVersion, error) {
+	var v models.PuzzleImageVersion
+	var variants []byte
+	if err := row.Scan(&v.ID, &v.PuzzleID, &v.ImageURL, &v.ImagePath, &v.ImageSHA256, &v.ImageSize, &variants,
+		&v.BlurHash, &v.DominantColor, &v.CreatedAt, &v.Current); err != nil {
+		return v, err
+	}
+	if err := parseImageVariants(variants, &v.ImageVariants); err != nil {
+		return v, err
+	}
+	return v, nil
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Job run statuses recorded in the jobs table
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// JobLock is a held PostgreSQL advisory lock for a named job
+// Advisory locks belong to a database session, so the lock keeps its own connection
+// until Release is called. If the process dies the session ends and PostgreSQL frees the lock.
+type JobLock struct {
+	db     *DB
+	conn   *sql.Conn
+	name   string
+	key    int64
+	holder string
+}
+
+// TryLockJob tries to take the advisory lock for a job without waiting
+// Returns a nil lock (and nil error) when another session already holds it.
+// The holder is recorded in the jobs table while the lock is held.
+func (db *DB) TryLockJob(ctx context.Context, name, holder string) (*JobLock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for job lock: %w", err)
+	}
+
+	key := jobLockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to try job lock %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	lock := &JobLock{db: db, conn: conn, name: name, key: key, holder: holder}
+
+	query := `
+		INSERT INTO jobs (name, lock_holder, locked_at, last_status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name)
+		DO UPDATE SET
+			lock_holder = EXCLUDED.lock_holder,
+			locked_at = EXCLUDED.locked_at,
+			last_status = EXCLUDED.last_status
+	`
+	if _, err := conn.ExecContext(ctx, query, name, holder, time.Now(), JobStatusRunning); err != nil {
+		lock.unlock()
+		return nil, fmt.Errorf("failed to record job lock holder: %w", err)
+	}
+
+	return lock, nil
+}
+
+// Release records the run's outcome in the jobs table and frees the lock
+func (l *JobLock) Release(runErr error) error {
+	defer l.unlock()
+
+	status, lastError := JobStatusSucceeded, ""
+	if runErr != nil {
+		status, lastError = JobStatusFailed, runErr.Error()
+	}
+
+	query := `
+		UPDATE jobs
+		SET lock_holder = NULL, locked_at = NULL, last_holder = $2,
+			last_finished_at = $3, last_status = $4, last_error = $5
+		WHERE name = $1
+	`
+	if _, err := l.conn.ExecContext(context.Background(), query, l.name, l.holder, time.Now(), status, lastError); err != nil {
+		return fmt.Errorf("failed to record job result: %w", err)
+	}
+
+	return nil
+}
+
+// unlock frees the advisory lock and returns the connection to the pool
+func (l *JobLock) unlock() {
+	l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+}
+
+// GetJobLockHolder returns who currently holds a job's lock, or "" if nobody does
+func (db *DB) GetJobLockHolder(name string) (string, error) {
+	query := `SELECT COALESCE(lock_holder, '') FROM jobs WHERE name = $1`
+
+	var holder string
+	err := db.QueryRow(query, name).Scan(&holder)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get job lock holder: %w", err)
+	}
+
+	return holder, nil
+}
+
+// jobLockKey maps a job name to the bigint key used by pg_try_advisory_lock
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("rebus-job:" + name))
+	return int64(h.Sum64())
+}
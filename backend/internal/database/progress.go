@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+// GetPlayerProgress retrieves a player's progress on a puzzle
+// Returns an in-progress record with zero attempts if the player hasn't guessed yet
+func (db *DB) GetPlayerProgress(playerID, puzzleID string) (*models.PlayerProgress, error) {
+	query := `
+		SELECT player_id, puzzle_id, attempts, status, updated_at
+		FROM player_progress
+		WHERE player_id = $1 AND puzzle_id = $2
+	`
+
+	var p models.PlayerProgress
+	err := db.QueryRow(query, playerID, puzzleID).Scan(&p.PlayerID, &p.PuzzleID, &p.Attempts, &p.Status, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.PlayerProgress{
+			PlayerID: playerID,
+			PuzzleID: puzzleID,
+			Status:   models.ProgressInProgress,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player progress: %w", err)
+	}
+
+	return &p, nil
+}
+
+// RecordAttempt records a guess for a player on a puzzle (transactional)
+// The attempt is only counted while the puzzle is still in progress for the player;
+// recorded is false when the puzzle was already solved or failed.
+// maxAttempts <= 0 means attempts are unlimited.
+func (db *DB) RecordAttempt(playerID, puzzleID string, correct bool, maxAttempts int) (progress *models.PlayerProgress, recorded bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO player_progress (player_id, puzzle_id, attempts, status, updated_at)
+		VALUES ($1, $2, 0, $3, $4)
+		ON CONFLICT (player_id, puzzle_id) DO NOTHING
+	`
+	if _, err := tx.Exec(insertQuery, playerID, puzzleID, models.ProgressInProgress, time.Now()); err != nil {
+		return nil, false, fmt.Errorf("failed to create player progress: %w", err)
+	}
+
+	// Lock the row so concurrent guesses can't exceed the attempt limit
+	selectQuery := `
+		SELECT player_id, puzzle_id, attempts, status, updated_at
+		FROM player_progress
+		WHERE player_id = $1 AND puzzle_id = $2
+		FOR UPDATE
+	`
+	var p models.PlayerProgress
+	if err := tx.QueryRow(selectQuery, playerID, puzzleID).Scan(&p.PlayerID, &p.PuzzleID, &p.Attempts, &p.Status, &p.UpdatedAt); err != nil {
+		return nil, false, fmt.Errorf("failed to lock player progress: %w", err)
+	}
+
+	if p.IsFinished() {
+		return &p, false, nil
+	}
+
+	p.Attempts++
+	p.UpdatedAt = time.Now()
+	if correct {
+		p.Status = models.ProgressSolved
+	} else if maxAttempts > 0 && p.Attempts >= maxAttempts {
+		p.Status = models.ProgressFailed
+	}
+
+	updateQuery := `
+		UPDATE player_progress
+		SET attempts = $3, status = $4, updated_at = $5
+		WHERE player_id = $1 AND puzzle_id = $2
+	`
+	if _, err := tx.Exec(updateQuery, playerID, puzzleID, p.Attempts, p.Status, p.UpdatedAt); err != nil {
+		return nil, false, fmt.Errorf("failed to update player progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &p, true, nil
+}
+
+// MarkPuzzleFailed marks a puzzle as failed (given up) for a player
+// A puzzle the player has already solved keeps its solved status
+func (db *DB) MarkPuzzleFailed(playerID, puzzleID string) (*models.PlayerProgress, error) {
+	query := `
+		INSERT INTO player_progress (player_id, puzzle_id, attempts, status, updated_at)
+		VALUES ($1, $2, 0, $3, $4)
+		ON CONFLICT (player_id, puzzle_id)
+		DO UPDATE SET
+			status = CASE WHEN player_progress.status = $5 THEN player_progress.status ELSE EXCLUDED.status END,
+			updated_at = EXCLUDED.updated_at
+		RETURNING player_id, puzzle_id, attempts, status, updated_at
+	`
+
+	var p models.PlayerProgress
+	err := db.QueryRow(query, playerID, puzzleID, models.ProgressFailed, time.Now(), models.ProgressSolved).
+		Scan(&p.PlayerID, &p.PuzzleID, &p.Attempts, &p.Status, &p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark puzzle as failed: %w", err)
+	}
+
+	return &p, nil
+}
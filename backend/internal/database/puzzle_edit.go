@@ -0,0 +1,379 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"backend/internal/models"
+)
+
+// ErrPuzzleNotFound is returned when an edited puzzle doesn't exist
+var ErrPuzzleNotFound = errors.New("puzzle not found")
+
+// ErrInvalidOrder is returned when a reorder doesn't list exactly the day's puzzles
+var ErrInvalidOrder = errors.New("order must list every puzzle of the day exactly once")
+
+// ErrPuzzleExists is returned when a created puzzle's ID or date and index are already taken
+var ErrPuzzleExists = errors.New("a puzzle already exists at that date and index")
+
+// CreatePuzzle inserts a new puzzle and records it in puzzle_audit, in one transaction
+// Unlike SavePuzzle it never overwrites an existing puzzle.
+func (db *DB) CreatePuzzle(p *models.Puzzle, actor string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO puzzles (id, date, index_num, image_url, image_path, answer, alternate_answers, hint, explanation, theme, difficulty, status, created_at, image_variants,
+			blur_hash, dominant_color, image_sha256, image_size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := tx.Exec(query,
+		p.ID,
+		p.Date,
+		p.Index,
+		p.ImageURL,
+		p.ImagePath,
+		p.Answer,
+		pq.Array(alternateAnswers(p)),
+		p.Hint,
+		p.Explanation,
+		p.Theme,
+		p.Difficulty,
+		initialStatus(p),
+		time.Now(),
+		imageVariantsJSON(p.ImageVariants),
+		p.BlurHash,
+		p.DominantColor,
+		p.ImageSHA256,
+		p.ImageSize,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create puzzle: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create puzzle: %w", err)
+	}
+	if inserted == 0 {
+		return ErrPuzzleExists
+	}
+
+	if err := recordImageVersion(tx, p); err != nil {
+		return err
+	}
+
+	if err := insertAudit(tx, p.ID, models.AuditCreate, actor, nil, p); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePuzzle applies edit to a puzzle and records the change in puzzle_audit, in one transaction
+// The puzzle row is locked while edit runs; an error from edit aborts the change and is returned as is.
+func (db *DB) UpdatePuzzle(id, action, actor string, edit func(p *models.Puzzle) error) (*models.Puzzle, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockPuzzle(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *p
+	before.AlternateAnswers = append([]string(nil), p.AlternateAnswers...)
+
+	if err := edit(p); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE puzzles
+		SET image_url = $2, image_path = $3, answer = $4, alternate_answers = $5, hint = $6,
+			explanation = $7, theme = $8, difficulty = $9, status = $10, review_notes = $11,
+			reviewed_by = $12, reviewed_at = $13, image_variants = $14,
+			blur_hash = $15, dominant_color = $16, image_sha256 = $17, image_size = $18,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err = tx.QueryRow(query,
+		p.ID,
+		p.ImageURL,
+		p.ImagePath,
+		p.Answer,
+		pq.Array(alternateAnswers(p)),
+		p.Hint,
+		p.Explanation,
+		p.Theme,
+		p.Difficulty,
+		p.Status,
+		p.ReviewNotes,
+		p.ReviewedBy,
+		p.ReviewedAt,
+		imageVariantsJSON(p.ImageVariants),
+		p.BlurHash,
+		p.DominantColor,
+		p.ImageSHA256,
+		p.ImageSize,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update puzzle: %w", err)
+	}
+
+	if err := recordImageVersion(tx, p); err != nil {
+		return nil, err
+	}
+
+	if err := insertAudit(tx, id, action, actor, &before, p); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return p, nil
+}
+
+// DeletePuzzle deletes a puzzle along with its player progress, stats and image versions, recording it in puzzle_audit
+// The images are left in storage for the image GC job.
+func (db *DB) DeletePuzzle(id, actor string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := lockPuzzle(tx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM player_progress WHERE puzzle_id = $1`,
+		`DELETE FROM puzzle_stats WHERE puzzle_id = $1`,
+		`DELETE FROM puzzle_image_versions WHERE puzzle_id = $1`,
+		`DELETE FROM puzzles WHERE id = $1`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("failed to delete puzzle: %w", err)
+		}
+	}
+
+	if err := insertAudit(tx, id, models.AuditDelete, actor, p, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReorderPuzzles renumbers a day's puzzles in the given order and returns them in that order
+// IDs are rewritten to {date}-{index} to match, along with the player progress, stats, audit
+// entries and image versions that refer to them, so everything stays attached to the same puzzles.
+func (db *DB) ReorderPuzzles(date string, ids []string, actor string) ([]models.Puzzle, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + puzzleColumns + `
+		FROM puzzles
+		WHERE date = $1
+		ORDER BY index_num ASC
+		FOR UPDATE
+	`
+	rows, err := tx.Query(query, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query puzzles: %w", err)
+	}
+	current := make(map[string]models.Puzzle)
+	for rows.Next() {
+		p, err := scanPuzzle(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan puzzle: %w", err)
+		}
+		current[p.ID] = *p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating puzzles: %w", err)
+	}
+
+	if len(ids) != len(current) {
+		return nil, ErrInvalidOrder
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := current[id]; !ok || seen[id] {
+			return nil, ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	// Move every index and ID out of the way first so the UNIQUE(date, index_num) constraint
+	// and the primary keys hold at each step
+	if _, err := tx.Exec(`UPDATE puzzles SET index_num = -1 - index_num WHERE date = $1`, date); err != nil {
+		return nil, fmt.Errorf("failed to reorder puzzles: %w", err)
+	}
+	for _, id := range ids {
+		if err := renamePuzzle(tx, id, reorderingPrefix+id); err != nil {
+			return nil, err
+		}
+	}
+
+	reordered := make([]models.Puzzle, len(ids))
+	for i, id := range ids {
+		newID := fmt.Sprintf("%s-%d", date, i)
+		if err := renamePuzzle(tx, reorderingPrefix+id, newID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`UPDATE puzzles SET index_num = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, newID, i); err != nil {
+			return nil, fmt.Errorf("failed to reorder puzzles: %w", err)
+		}
+
+		before := current[id]
+		after := before
+		after.ID = newID
+		after.Index = i
+		reordered[i] = after
+
+		if before.Index != after.Index || before.ID != after.ID {
+			if err := insertAudit(tx, newID, models.AuditReorder, actor, &before, &after); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return reordered, nil
+}
+
+// reorderingPrefix marks a puzzle's temporary ID while ReorderPuzzles moves it
+const reorderingPrefix = "reordering:"
+
+// puzzleIDTables are the tables other than puzzles that refer to a puzzle by ID
+var puzzleIDTables = []string{"player_progress", "puzzle_stats", "puzzle_audit", "puzzle_image_versions"}
+
+// renamePuzzle changes a puzzle's ID along with every row that refers to it
+func renamePuzzle(tx *sql.Tx, from, to string) error {
+	if _, err := tx.Exec(`UPDATE puzzles SET id = $2 WHERE id = $1`, from, to); err != nil {
+		return fmt.Errorf("failed to rename puzzle %s: %w", from, err)
+	}
+	for _, table := range puzzleIDTables {
+		if _, err := tx.Exec(`UPDATE `+table+` SET puzzle_id = $2 WHERE puzzle_id = $1`, from, to); err != nil {
+			return fmt.Errorf("failed to rename puzzle %s in %s: %w", from, table, err)
+		}
+	}
+	return nil
+}
+
+// ListPuzzleAudit returns a puzzle's audit history, newest first
+func (db *DB) ListPuzzleAudit(puzzleID string) ([]models.PuzzleAuditEntry, error) {
+	query := `
+		SELECT id, puzzle_id, action, actor, before, after, created_at
+		FROM puzzle_audit
+		WHERE puzzle_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := db.Query(query, puzzleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query puzzle audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.PuzzleAuditEntry{}
+	for rows.Next() {
+		var entry models.PuzzleAuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.PuzzleID, &entry.Action, &entry.Actor, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan puzzle audit: %w", err)
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating puzzle audit: %w", err)
+	}
+
+	return entries, nil
+}
+
+// lockPuzzle selects a puzzle FOR UPDATE inside a transaction
+func lockPuzzle(tx *sql.Tx, id string) (*models.Puzzle, error) {
+	query := `SELECT ` + puzzleColumns + ` FROM puzzles WHERE id = $1 FOR UPDATE`
+
+	p, err := scanPuzzle(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPuzzleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get puzzle: %w", err)
+	}
+
+	return p, nil
+}
+
+// insertAudit records a change to a puzzle; before or after is nil for creates and deletes
+func insertAudit(tx *sql.Tx, puzzleID, action, actor string, before, after *models.Puzzle) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO puzzle_audit (puzzle_id, action, actor, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.Exec(query, puzzleID, action, actor, beforeJSON, afterJSON, time.Now()); err != nil {
+		return fmt.Errorf("failed to record puzzle audit: %w", err)
+	}
+
+	return nil
+}
+
+// auditJSON encodes a puzzle snapshot for the audit table, or NULL for nil
+func auditJSON(p *models.Puzzle) (interface{}, error) {
+	if p == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode puzzle audit: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+// UpdateRateLimitBucket applies update to a token bucket while holding its row lock (transactional)
+// A missing bucket is created with capacity tokens, so update always sees the current state.
+func (db *DB) UpdateRateLimitBucket(key string, capacity float64, update func(b *models.RateLimitBucket)) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO rate_limits (key, tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING
+	`
+	if _, err := tx.Exec(insertQuery, key, capacity, time.Now()); err != nil {
+		return fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+
+	// Lock the row so concurrent requests on other replicas can't spend the same token
+	selectQuery := `SELECT key, tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE`
+	var b models.RateLimitBucket
+	if err := tx.QueryRow(selectQuery, key).Scan(&b.Key, &b.Tokens, &b.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to lock rate limit bucket: %w", err)
+	}
+
+	update(&b)
+
+	updateQuery := `UPDATE rate_limits SET tokens = $2, updated_at = $3 WHERE key = $1`
+	if _, err := tx.Exec(updateQuery, key, b.Tokens, b.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rate limit bucket: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRateLimitsBefore removes buckets that haven't been used since cutoff
+// A deleted bucket is recreated full, so this only forgets clients that have been idle.
+func (db *DB) DeleteRateLimitsBefore(cutoff time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM rate_limits WHERE updated_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rate limit buckets: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rate limit buckets: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetReleaseTime retrieves the release time override for a date
+// found is false when the date uses the default release hour
+func (db *DB) GetReleaseTime(date string) (releaseAt time.Time, found bool, err error) {
+	query := `SELECT release_at FROM puzzle_releases WHERE date = $1`
+
+	err = db.QueryRow(query, date).Scan(&releaseAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get release time: %w", err)
+	}
+
+	return releaseAt, true, nil
+}
+
+// SetReleaseTime sets the release time override for a date
+func (db *DB) SetReleaseTime(date string, releaseAt time.Time) error {
+	query := `
+		INSERT INTO puzzle_releases (date, release_at, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (date)
+		DO UPDATE SET
+			release_at = EXCLUDED.release_at,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := db.Exec(query, date, releaseAt, time.Now()); err != nil {
+		return fmt.Errorf("failed to set release time: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReleaseTime removes the release time override for a date
+func (db *DB) DeleteReleaseTime(date string) error {
+	query := `DELETE FROM puzzle_releases WHERE date = $1`
+
+	if _, err := db.Exec(query, date); err != nil {
+		return fmt.Errorf("failed to delete release time: %w", err)
+	}
+
+	return nil
+}
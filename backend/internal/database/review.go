@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"backend/internal/models"
+)
+
+// GetPlayablePuzzlesForDate retrieves the puzzles for a date that have passed review
+func (db *DB) GetPlayablePuzzlesForDate(date string) ([]models.Puzzle, error) {
+	query := `
+		SELECT ` + puzzleColumns + `
+		FROM puzzles
+		WHERE date = $1 AND status = ANY($2)
+		ORDER BY index_num ASC
+	`
+
+	return db.queryPuzzles(query, date, pq.Array(playableStatuses))
+}
+
+// HasPlayablePuzzlesForDate checks if a date has puzzles that have passed review
+func (db *DB) HasPlayablePuzzlesForDate(date string) (bool, error) {
+	query := `SELECT COUNT(*) FROM puzzles WHERE date = $1 AND status = ANY($2)`
+	var count int
+	err := db.QueryRow(query, date, pq.Array(playableStatuses)).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check playable puzzles: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetPlayableImageDate returns the earliest date of a playable puzzle that uses an image
+// found is false when no playable puzzle does, e.g. for a draft or an unpromoted bank image.
+func (db *DB) GetPlayableImageDate(imagePath string) (date string, found bool, err error) {
+	query := `
+		SELECT date
+		FROM puzzles
+		WHERE image_path = $1 AND status = ANY($2)
+		ORDER BY date ASC
+		LIMIT 1
+	`
+
+	err = db.QueryRow(query, imagePath, pq.Array(playableStatuses)).Scan(&date)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up image: %w", err)
+	}
+
+	return date, true, nil
+}
+
+// ListPendingPuzzles retrieves draft and in-review puzzles, oldest date first
+func (db *DB) ListPendingPuzzles() ([]models.Puzzle, error) {
+	query := `
+		SELECT ` + puzzleColumns + `
+		FROM puzzles
+		WHERE status = ANY($1)
+		ORDER BY date ASC, index_num ASC
+	`
+
+	return db.queryPuzzles(query, pq.Array([]string{models.PuzzleDraft, models.PuzzleInReview}))
+}
+
+// ReviewPuzzle moves a puzzle to a new review status if it's currently in one of from
+// Returns ErrPuzzleNotFound if the puzzle doesn't exist and nil if it isn't in one of the from statuses.
+func (db *DB) ReviewPuzzle(id, status, notes, reviewer string, from []string) (*models.Puzzle, error) {
+	action := models.AuditApprove
+	if status == models.PuzzleRejected {
+		action = models.AuditReject
+	}
+
+	p, err := db.UpdatePuzzle(id, action, reviewer, func(p *models.Puzzle) error {
+		for _, allowed := range from {
+			if p.Status == allowed {
+				now := time.Now()
+				p.Status = status
+				p.ReviewNotes = notes
+				p.ReviewedBy = reviewer
+				p.ReviewedAt = &now
+				return nil
+			}
+		}
+		return errStatusConflict
+	})
+	if errors.Is(err, errStatusConflict) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review puzzle: %w", err)
+	}
+
+	return p, nil
+}
+
+// errStatusConflict aborts a review whose transition isn't allowed
+var errStatusConflict = errors.New("puzzle status does not allow this transition")
+
+// PublishApprovedPuzzles marks approved puzzles dated on or before through as published
+func (db *DB) PublishApprovedPuzzles(through string) (int64, error) {
+	query := `UPDATE puzzles SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE status = $2 AND date <= $3`
+
+	result, err := db.Exec(query, models.PuzzlePublished, models.PuzzleApproved, through)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish puzzles: %w", err)
+	}
+
+	published, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish puzzles: %w", err)
+	}
+
+	return published, nil
+}
+
+// queryPuzzles runs a query selecting puzzleColumns and scans every row
+func (db *DB) queryPuzzles(query string, args ...interface{}) ([]models.Puzzle, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query puzzles: %w", err)
+	}
+	defer rows.Close()
+
+	var puzzles []models.Puzzle
+	for rows.Next() {
+		p, err := scanPuzzle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan puzzle: %w", err)
+		}
+		puzzles = append(puzzles, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating puzzles: %w", err)
+	}
+
+	return puzzles, nil
+}
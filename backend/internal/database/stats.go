@@ -0,0 +1,63 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeletePlayerProgressBefore deletes player progress last updated before cutoff
+func (db *DB) DeletePlayerProgressBefore(cutoff time.Time) (int64, error) {
+	query := `DELETE FROM player_progress WHERE updated_at < $1`
+
+	result, err := db.Exec(query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete player progress: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted player progress: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// RollupPuzzleStats recomputes player statistics for puzzles on or after since
+// Returns the number of puzzles whose statistics were updated
+func (db *DB) RollupPuzzleStats(since string) (int64, error) {
+	query := `
+		INSERT INTO puzzle_stats (puzzle_id, date, players, solved, failed, attempts, updated_at)
+		SELECT
+			p.id,
+			p.date,
+			COUNT(pp.player_id),
+			COUNT(*) FILTER (WHERE pp.status = 'solved'),
+			COUNT(*) FILTER (WHERE pp.status = 'failed'),
+			COALESCE(SUM(pp.attempts), 0),
+			$2
+		FROM puzzles p
+		LEFT JOIN player_progress pp ON pp.puzzle_id = p.id
+		WHERE p.date >= $1
+		GROUP BY p.id, p.date
+		ON CONFLICT (puzzle_id)
+		DO UPDATE SET
+			date = EXCLUDED.date,
+			players = EXCLUDED.players,
+			solved = EXCLUDED.solved,
+			failed = EXCLUDED.failed,
+			attempts = EXCLUDED.attempts,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	result, err := db.Exec(query, since, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up puzzle stats: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rolled up puzzle stats: %w", err)
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+// tokenColumns is the column list read by scanAPIToken
+const tokenColumns = `id, name, role, token_hash, created_by, created_at, last_used_at, revoked_at`
+
+// scanAPIToken scans a row selected with tokenColumns
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var t models.APIToken
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &t.Role, &t.TokenHash, &t.CreatedBy, &t.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
+
+// CreateAPIToken stores a new API token by its hash
+func (db *DB) CreateAPIToken(name, role, tokenHash, createdBy string) (*models.APIToken, error) {
+	query := `
+		INSERT INTO api_tokens (name, role, token_hash, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + tokenColumns
+
+	t, err := scanAPIToken(db.QueryRow(query, name, role, tokenHash, createdBy, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	return t, nil
+}
+
+// GetAPITokenByHash returns the unrevoked token with the given hash, or nil if there isn't one
+func (db *DB) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE token_hash = $1 AND revoked_at IS NULL`
+
+	t, err := scanAPIToken(db.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	return t, nil
+}
+
+// TouchAPIToken records that a token was used, at most once a minute
+func (db *DB) TouchAPIToken(id int) error {
+	query := `
+		UPDATE api_tokens SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')
+	`
+
+	if _, err := db.Exec(query, id, time.Now()); err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+
+	return nil
+}
+
+// ListAPITokens returns all tokens, including revoked ones, newest first
+func (db *DB) ListAPITokens() ([]models.APIToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes a token; returns false if no unrevoked token has that ID
+func (db *DB) RevokeAPIToken(id int) (bool, error) {
+	query := `UPDATE api_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`
+
+	result, err := db.Exec(query, id, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/release"
+	"backend/internal/scheduler"
+	"backend/internal/store"
+)
+
+// AdminHandler handles admin-only HTTP requests
+// Routes are expected to be mounted behind auth middleware.
+type AdminHandler struct {
+	store     *store.Store
+	policy    *release.Policy
+	scheduler *scheduler.Scheduler
+	auth      *auth.Authenticator
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(store *store.Store, policy *release.Policy, sched *scheduler.Scheduler, authenticator *auth.Authenticator) *AdminHandler {
+	return &AdminHandler{
+		store:     store,
+		policy:    policy,
+		scheduler: sched,
+		auth:      authenticator,
+	}
+}
+
+// GetScheduleHandler handles GET /api/admin/schedule
+// Lists every registered job with its cron expression and next run time
+func (h *AdminHandler) GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"timezone": store.PublicationLocation().String(),
+		"jobs":     h.scheduler.Jobs(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// StartBackfillHandler handles POST /api/admin/backfill
+// Starts generating puzzles for a date range in the background; poll the returned id for per-date results
+func (h *AdminHandler) StartBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.BackfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	backfill, err := h.scheduler.StartBackfill(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := backfill.Report()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/admin/backfill/"+report.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetBackfillHandler handles GET /api/admin/backfill/{id}
+// Backfills are tracked in memory, so this must reach the instance that started the backfill
+func (h *AdminHandler) GetBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	backfill := h.scheduler.GetBackfill(mux.Vars(r)["id"])
+	if backfill == nil {
+		http.Error(w, "Backfill not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(backfill.Report()); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ListBankHandler handles GET /api/admin/bank
+// Lists reserve puzzle sets with their answers and images; ?all=true includes sets already promoted
+func (h *AdminHandler) ListBankHandler(w http.ResponseWriter, r *http.Request) {
+	sets, err := h.store.ListBankSets(r.URL.Query().Get("all") == "true")
+	if err != nil {
+		http.Error(w, "Failed to list bank sets", http.StatusInternalServerError)
+		return
+	}
+	if sets == nil {
+		sets = []models.BankSet{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"sets": sets}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ApproveBankSetHandler handles POST /api/admin/bank/{setId}/approve
+// Only approved sets are promoted when generation fails
+func (h *AdminHandler) ApproveBankSetHandler(w http.ResponseWriter, r *http.Request) {
+	approved, err := h.store.ApproveBankSet(mux.Vars(r)["setId"])
+	if err != nil {
+		http.Error(w, "Failed to approve bank set", http.StatusInternalServerError)
+		return
+	}
+	if !approved {
+		http.Error(w, "Bank set not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOrphanedImagesHandler handles GET /api/admin/images/orphans
+// A dry run of the image GC job: lists the images it would delete or quarantine now
+func (h *AdminHandler) GetOrphanedImagesHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := h.scheduler.FindOrphanedImages()
+	if err != nil {
+		log.Printf("Failed to find orphaned images: %v", err)
+		http.Error(w, "Failed to find orphaned images", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// GetReleaseHandler handles GET /api/admin/releases/{date}
+func (h *AdminHandler) GetReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if err := store.ValidateDate(date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeRelease(w, date)
+}
+
+// SetReleaseHandler handles PUT /api/admin/releases/{date}
+// Overrides the release time so a date can be published earlier or later than the default hour
+func (h *AdminHandler) SetReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if err := store.ValidateDate(date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ReleaseAt.IsZero() {
+		http.Error(w, "releaseAt is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.SetReleaseTime(date, req.ReleaseAt); err != nil {
+		http.Error(w, "Failed to set release time", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRelease(w, date)
+}
+
+// DeleteReleaseHandler handles DELETE /api/admin/releases/{date}
+// Reverts the date to the default release hour
+func (h *AdminHandler) DeleteReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if err := store.ValidateDate(date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.DeleteReleaseTime(date); err != nil {
+		http.Error(w, "Failed to delete release time", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRelease(w, date)
+}
+
+// writeRelease writes the current release state of a date
+func (h *AdminHandler) writeRelease(w http.ResponseWriter, date string) {
+	_, override, err := h.store.GetReleaseTime(date)
+	if err != nil {
+		http.Error(w, "Failed to load release time", http.StatusInternalServerError)
+		return
+	}
+
+	releaseAt, err := h.policy.ReleaseTime(date, nil)
+	if err != nil {
+		http.Error(w, "Failed to load release time", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.ReleaseResponse{
+		Date:      date,
+		ReleaseAt: releaseAt,
+		Released:  !time.Now().Before(releaseAt),
+		Override:  override,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/release"
+	"backend/internal/store"
+)
+
+const (
+	defaultArchivePageSize = 20
+	maxArchivePageSize     = 100
+)
+
+// ArchiveHandler handles browsing of past puzzle days
+type ArchiveHandler struct {
+	store  *store.Store
+	policy *release.Policy
+}
+
+// NewArchiveHandler creates a new archive handler
+func NewArchiveHandler(store *store.Store, policy *release.Policy) *ArchiveHandler {
+	return &ArchiveHandler{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// GetArchiveHandler handles GET /api/archive
+// Query parameters: cursor (YYYY-MM-DD), limit, month (YYYY-MM), theme, difficulty
+func (h *ArchiveHandler) GetArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseArchiveFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	playerLoc, err := playerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Never list days that haven't been released yet
+	filter.Through, err = h.policy.LatestReleasedDate(time.Now(), playerLoc)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+
+	// Fetch one extra day to know whether there is another page
+	pageSize := filter.Limit
+	filter.Limit = pageSize + 1
+
+	days, err := h.store.ListArchiveDays(filter)
+	if err != nil {
+		http.Error(w, "Failed to load archive", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.ArchiveResponse{
+		Days: days,
+	}
+	if len(days) > pageSize {
+		response.Days = days[:pageSize]
+		response.NextCursor = response.Days[pageSize-1].Date
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseArchiveFilter validates the archive query parameters
+func parseArchiveFilter(r *http.Request) (models.ArchiveFilter, error) {
+	query := r.URL.Query()
+	filter := models.ArchiveFilter{
+		Before:     query.Get("cursor"),
+		Month:      query.Get("month"),
+		Theme:      strings.TrimSpace(query.Get("theme")),
+		Difficulty: strings.ToLower(query.Get("difficulty")),
+		Limit:      defaultArchivePageSize,
+	}
+
+	if filter.Before != "" {
+		if err := store.ValidateDate(filter.Before); err != nil {
+			return filter, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	if filter.Month != "" {
+		if _, err := time.Parse("2006-01", filter.Month); err != nil || len(filter.Month) != 7 {
+			return filter, fmt.Errorf("invalid month format. Use YYYY-MM")
+		}
+	}
+
+	if filter.Difficulty != "" && !models.IsValidDifficulty(filter.Difficulty) {
+		return filter, fmt.Errorf("invalid difficulty. Use easy, medium or hard")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+		if n > maxArchivePageSize {
+			n = maxArchivePageSize
+		}
+		filter.Limit = n
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/store"
+)
+
+// Cache lifetimes for puzzles and images
+const (
+	pastMaxAge  = 24 * time.Hour       // Puzzles and images for days that are over; edits and regenerations are rare but possible
+	todayMaxAge = time.Minute          // Today's and future puzzles, which may still be regenerated or edited
+	imageMaxAge = 365 * 24 * time.Hour // Content-addressed images, which never change
+)
+
+// isPastDate reports whether a date is over in every timezone
+// Players can be up to a day behind the publication timezone, so only dates before yesterday count.
+func isPastDate(date string) bool {
+	yesterday := time.Now().In(store.PublicationLocation()).AddDate(0, 0, -1).Format("2006-01-02")
+	return date < yesterday
+}
+
+// cacheControl returns the Cache-Control value for content belonging to a date
+// Past dates may be cached for a day and revalidated with their ETag after that; anything else briefly.
+func cacheControl(date string) string {
+	if !isPastDate(date) {
+		return maxAge(todayMaxAge)
+	}
+	return maxAge(pastMaxAge)
+}
+
+// maxAge formats a public Cache-Control max-age directive
+func maxAge(d time.Duration) string {
+	return "public, max-age=" + strconv.Itoa(int(d.Seconds()))
+}
+
+// contentETag returns a strong ETag from the SHA-256 of content
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// serveCached writes content with an ETag and Last-Modified, answering conditional requests with 304
+// Content-Type and Cache-Control should already be set.
+func serveCached(w http.ResponseWriter, r *http.Request, content []byte, lastModified time.Time) {
+	w.Header().Set("ETag", contentETag(content))
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(content))
+}
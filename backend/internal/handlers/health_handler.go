@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"backend/internal/database"
+	"backend/internal/scheduler"
+)
+
+// HealthHandler reports service health
+type HealthHandler struct {
+	db        *database.DB
+	scheduler *scheduler.Scheduler
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db *database.DB, sched *scheduler.Scheduler) *HealthHandler {
+	return &HealthHandler{
+		db:        db,
+		scheduler: sched,
+	}
+}
+
+// ServeHealth handles GET /health
+// The endpoint is public, so errors are logged rather than returned.
+// Returns 503 when the database is unreachable. A short puzzle buffer is reported as
+// "degraded" but still returns 200 so the instance isn't restarted for it.
+func (h *HealthHandler) ServeHealth(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"status":   "ok",
+		"database": "ok",
+	}
+	statusCode := http.StatusOK
+
+	if err := h.db.PingContext(r.Context()); err != nil {
+		log.Printf("Health check: database unavailable: %v", err)
+		response["status"] = "unavailable"
+		response["database"] = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	} else if buffer, err := h.scheduler.BufferStatus(); err != nil {
+		log.Printf("Health check: failed to check puzzle buffer: %v", err)
+		response["status"] = "degraded"
+		response["buffer"] = "unavailable"
+	} else {
+		response["buffer"] = buffer
+		if !buffer.Healthy() {
+			response["status"] = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachedImage is an image read from object storage, or a record that the key doesn't exist
+type cachedImage struct {
+	key          string
+	data         []byte // nil when the key doesn't exist
+	contentType  string
+	etag         string
+	lastModified time.Time
+	fetchedAt    time.Time
+}
+
+// fresh reports whether a cached image can still be served at now
+// Content-addressed images never change, so they stay fresh until evicted. Past days' images are
+// fetched again after pastMaxAge, in case they were regenerated in place, and anything else,
+// including a missing key, after todayMaxAge.
+func (c *cachedImage) fresh(now time.Time) bool {
+	if c.data != nil && strings.HasPrefix(c.key, "sha256/") {
+		return true
+	}
+	if c.data != nil && len(c.key) >= 10 && isPastDate(c.key[:10]) {
+		return now.Sub(c.fetchedAt) < pastMaxAge
+	}
+	return now.Sub(c.fetchedAt) < todayMaxAge
+}
+
+// size is what an entry counts against the cache limit
+// The key is included so that records of missing keys can't pile up without bound.
+func (c *cachedImage) size() int64 {
+	return int64(len(c.key) + len(c.data))
+}
+
+// imageCache keeps recently proxied images in memory, evicting the least recently used beyond maxBytes
+type imageCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  map[string]*list.Element
+	order    *list.List // Most recently used at the front
+}
+
+// newImageCache creates a cache holding up to maxBytes of image data; 0 disables caching
+func newImageCache(maxBytes int64) *imageCache {
+	return &imageCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns a fresh cached image for key
+func (c *imageCache) get(key string, now time.Time) (*cachedImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	img := elem.Value.(*cachedImage)
+	if !img.fresh(now) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return img, true
+}
+
+// put adds or replaces an image, evicting older ones to stay within maxBytes
+func (c *imageCache) put(img *cachedImage) {
+	size := img.size()
+	if c.maxBytes <= 0 || size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[img.key]; ok {
+		c.remove(elem)
+	}
+	c.entries[img.key] = c.order.PushFront(img)
+	c.size += size
+
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry; the caller holds mu
+func (c *imageCache) remove(elem *list.Element) {
+	img := c.order.Remove(elem).(*cachedImage)
+	delete(c.entries, img.key)
+	c.size -= img.size()
+}
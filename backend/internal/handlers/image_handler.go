@@ -1,16 +1,55 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/release"
+	"backend/internal/store"
+)
+
+// Ways ImageHandler can serve images kept in Supabase
+const (
+	SupabaseModePublic   = "public"   // 302 to the public bucket URL
+	SupabaseModeProxy    = "proxy"    // Read from the bucket and serve from the backend, with an in-memory cache
+	SupabaseModeRedirect = "redirect" // 302 to a short-lived presigned URL, which also works for private buckets
 )
 
 // ImageHandler handles image serving
 type ImageHandler struct {
 	imagesPath      string
 	useSupabase     bool
+	supabaseMode    string
 	supabaseBaseURL string
+	supabase        *store.SupabaseStorage
+	presignTTL      time.Duration // How long presigned redirect URLs stay valid
+	cache           *imageCache   // Images read from the bucket in proxy mode
+
+	// Set by RequireRelease for a private bucket, to hold back images of unreleased puzzles
+	store  *store.Store
+	policy *release.Policy
+	auth   *auth.Authenticator
+
+	etagMu sync.Mutex
+	etags  map[string]fileETag // Content hashes of served files, by path
+}
+
+// fileETag is a cached content hash along with what's needed to tell whether the file has changed
+type fileETag struct {
+	etag    string
+	size    int64
+	modTime time.Time
 }
 
 // NewImageHandler creates a new image handler for file system storage
@@ -18,6 +57,7 @@ func NewImageHandler(imagesPath string) *ImageHandler {
 	return &ImageHandler{
 		imagesPath:  imagesPath,
 		useSupabase: false,
+		etags:       make(map[string]fileETag),
 	}
 }
 
@@ -25,11 +65,47 @@ func NewImageHandler(imagesPath string) *ImageHandler {
 func NewImageHandlerWithSupabase(supabaseBaseURL string) *ImageHandler {
 	return &ImageHandler{
 		useSupabase:     true,
-		supabaseBaseURL: supabaseBaseURL,
+		supabaseMode:    SupabaseModePublic,
+		supabaseBaseURL: strings.TrimSuffix(supabaseBaseURL, "/"),
+	}
+}
+
+// NewImageProxyHandler creates an image handler that serves images read from Supabase
+// Up to cacheBytes of recently served images are kept in memory.
+func NewImageProxyHandler(supabase *store.SupabaseStorage, cacheBytes int64) *ImageHandler {
+	return &ImageHandler{
+		useSupabase:  true,
+		supabaseMode: SupabaseModeProxy,
+		supabase:     supabase,
+		cache:        newImageCache(cacheBytes),
+	}
+}
+
+// NewImageRedirectHandler creates an image handler that redirects to presigned Supabase URLs valid for ttl
+func NewImageRedirectHandler(supabase *store.SupabaseStorage, ttl time.Duration) *ImageHandler {
+	return &ImageHandler{
+		useSupabase:  true,
+		supabaseMode: SupabaseModeRedirect,
+		supabase:     supabase,
+		presignTTL:   ttl,
 	}
 }
 
+// earliestTimezone is where a date's release comes first
+// Images are loaded without the player's timezone, so they're released as soon as any player can see their puzzle.
+var earliestTimezone = time.FixedZone("UTC+14", 14*60*60)
+
+// RequireRelease makes a Supabase handler serve only images of playable puzzles whose date
+// has been released, so that a private bucket's images stay hidden until then
+// Callers with an API token can load any image, like they can see any puzzle.
+func (h *ImageHandler) RequireRelease(store *store.Store, policy *release.Policy, authenticator *auth.Authenticator) {
+	h.store = store
+	h.policy = policy
+	h.auth = authenticator
+}
+
 // ServeImage handles GET /api/images/{filename}
+// With Supabase the filename may also be an object key such as 2025-01-15/0.png.
 func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
 	// Get filename from URL path
 	filename := strings.TrimPrefix(r.URL.Path, "/api/images/")
@@ -40,25 +116,54 @@ func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If using Supabase, redirect to the Supabase public URL
+	// Set CORS headers to allow frontend to load images
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle OPTIONS preflight request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	if h.useSupabase {
-		// Extract date and index from filename (format: YYYY-MM-DD-index.png)
-		// Parse to get date folder structure
-		parts := strings.Split(filename, "-")
-		if len(parts) >= 4 {
-			// Format: YYYY-MM-DD-index.png
-			date := strings.Join(parts[:3], "-")
-			indexWithExt := parts[3]
-			index := strings.TrimSuffix(indexWithExt, filepath.Ext(indexWithExt))
-			
-			// Redirect to Supabase URL: {baseURL}/{date}/{index}.png
-			supabaseURL := h.supabaseBaseURL + "/" + date + "/" + index + ".png"
-			http.Redirect(w, r, supabaseURL, http.StatusMovedPermanently)
-			return
+		key := supabaseKey(filename)
+		cacheControl := imageCacheControl(key)
+		if h.policy != nil {
+			visible, early, err := h.imageVisibility(r, key)
+			if err != nil {
+				log.Printf("Failed to check release of %s: %v", key, err)
+				http.Error(w, "Failed to load image", http.StatusInternalServerError)
+				return
+			}
+			if !visible {
+				http.Error(w, "Image not found", http.StatusNotFound)
+				return
+			}
+			if early {
+				// Only API tokens can see it yet, so shared caches mustn't keep it
+				cacheControl = "private, no-cache"
+			}
+		}
+
+		switch h.supabaseMode {
+		case SupabaseModeProxy:
+			h.proxyImage(w, r, key, cacheControl)
+		case SupabaseModeRedirect:
+			url, err := h.supabase.PresignGetURL(key, h.presignTTL)
+			if err != nil {
+				log.Printf("Failed to presign %s: %v", key, err)
+				http.Error(w, "Failed to load image", http.StatusInternalServerError)
+				return
+			}
+			// Browsers may reuse the redirect for half the URL's lifetime
+			w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(h.presignTTL.Seconds()/2)))
+			http.Redirect(w, r, url, http.StatusFound)
+		default:
+			// 302 rather than 301, which browsers cache forever and would tie images to this bucket
+			http.Redirect(w, r, h.supabaseBaseURL+"/"+key, http.StatusFound)
 		}
-		// Fallback: try to construct URL directly
-		supabaseURL := h.supabaseBaseURL + "/" + filename
-		http.Redirect(w, r, supabaseURL, http.StatusMovedPermanently)
 		return
 	}
 
@@ -72,30 +177,226 @@ func (h *ImageHandler) ServeImage(w http.ResponseWriter, r *http.Request) {
 	// Construct full path
 	imagePath := filepath.Join(h.imagesPath, filename)
 
-	// Set CORS headers to allow frontend to load images
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	// PNGs may have a WebP twin (see models.ImageVariantPath), served to clients that accept it
+	ext := filepath.Ext(filename)
+	if ext == ".png" {
+		w.Header().Set("Vary", "Accept")
+		if acceptsWebP(r) {
+			webpPath := strings.TrimSuffix(imagePath, ext) + ".webp"
+			if info, err := os.Stat(webpPath); err == nil && !info.IsDir() {
+				imagePath, ext = webpPath, ".webp"
+			}
+		}
+	}
 
-	// Handle OPTIONS preflight request
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", imageContentType(ext))
+
+	// Content-hash ETag, and a cache lifetime from the date the image belongs to
+	// http.ServeFile answers If-None-Match and If-Modified-Since with 304 Not Modified.
+	if info, err := os.Stat(imagePath); err == nil && !info.IsDir() {
+		etag, err := h.fileETag(imagePath, info)
+		if errors.Is(err, store.ErrImageCorrupt) {
+			log.Printf("Refusing to serve image: %v", err)
+			http.Error(w, "Failed to load image", http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			w.Header().Set("ETag", etag)
+		} else {
+			log.Printf("Failed to hash image %s: %v", imagePath, err)
+		}
+		w.Header().Set("Cache-Control", imageCacheControl(filename))
+	}
+
+	// Serve file
+	http.ServeFile(w, r, imagePath)
+}
+
+// imageVisibility reports whether the caller may load an image, and whether that's only
+// because the caller has an API token and the image's puzzle hasn't been released yet
+func (h *ImageHandler) imageVisibility(r *http.Request, key string) (visible, early bool, err error) {
+	date, found, err := h.store.GetPlayableImageDate(models.OriginalImagePath(key))
+	if err != nil {
+		return false, false, err
+	}
+	if found {
+		released, err := h.policy.IsReleased(date, time.Now(), earliestTimezone)
+		if err != nil {
+			return false, false, err
+		}
+		if released {
+			return true, false, nil
+		}
+	}
+	if h.auth.HasRole(r, auth.RoleViewer) {
+		return true, true, nil
+	}
+	return false, false, nil
+}
+
+// proxyImage serves an object from the bucket, negotiating WebP like file system serving does
+func (h *ImageHandler) proxyImage(w http.ResponseWriter, r *http.Request, key, cacheControl string) {
+	var img *cachedImage
+	var err error
+	if ext := path.Ext(key); ext == ".png" {
+		w.Header().Set("Vary", "Accept")
+		if acceptsWebP(r) {
+			img, err = h.cachedImage(strings.TrimSuffix(key, ext) + ".webp")
+			if err != nil && !errors.Is(err, store.ErrObjectNotFound) {
+				log.Printf("Failed to read %s from Supabase: %v", key, err)
+			}
+		}
+	}
+	if img == nil {
+		img, err = h.cachedImage(key)
+	}
+	if errors.Is(err, store.ErrObjectNotFound) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read %s from Supabase: %v", key, err)
+		http.Error(w, "Failed to load image", http.StatusBadGateway)
 		return
 	}
 
-	// Set content type
-	ext := filepath.Ext(filename)
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("ETag", img.etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, "", img.lastModified, bytes.NewReader(img.data))
+}
+
+// cachedImage returns an object from the proxy cache, reading it from the bucket on a miss
+// Missing keys are cached too, so clients asking for WebP twins of older images don't cost a request each.
+func (h *ImageHandler) cachedImage(key string) (*cachedImage, error) {
+	now := time.Now()
+	if img, ok := h.cache.get(key, now); ok {
+		if img.data == nil {
+			return nil, store.ErrObjectNotFound
+		}
+		return img, nil
+	}
+
+	object, err := h.supabase.GetObject(key)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		h.cache.put(&cachedImage{key: key, fetchedAt: now})
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := store.VerifyImage(key, object.Data); err != nil {
+		return nil, err
+	}
+
+	img := &cachedImage{
+		key:          key,
+		data:         object.Data,
+		contentType:  object.ContentType,
+		etag:         contentETag(object.Data),
+		lastModified: object.LastModified,
+		fetchedAt:    now,
+	}
+	if img.contentType == "" {
+		img.contentType = imageContentType(path.Ext(key))
+	}
+	h.cache.put(img)
+	return img, nil
+}
+
+// supabaseKey maps a requested filename to its object key
+// Keys are requested as they are; file system names map to keys like migrate-images maps them:
+// sha256-{hash}.png to sha256/{hash}.png and the older {date}-{index}.png to {date}/{index}.png.
+func supabaseKey(filename string) string {
+	if strings.Contains(filename, "/") {
+		return filename
+	}
+	if rest, ok := strings.CutPrefix(filename, "sha256-"); ok {
+		return "sha256/" + rest
+	}
+
+	// Format: YYYY-MM-DD-index.png, or YYYY-MM-DD-index-variant.ext for an image variant
+	parts := strings.Split(filename, "-")
+	if len(parts) >= 4 {
+		return strings.Join(parts[:3], "-") + "/" + strings.Join(parts[3:], "-")
+	}
+	return filename
+}
+
+// imageContentType returns the Content-Type for an image file extension
+func imageContentType(ext string) string {
 	switch ext {
 	case ".png":
-		w.Header().Set("Content-Type", "image/png")
+		return "image/png"
+	case ".webp":
+		return "image/webp"
 	case ".jpg", ".jpeg":
-		w.Header().Set("Content-Type", "image/jpeg")
+		return "image/jpeg"
 	case ".gif":
-		w.Header().Set("Content-Type", "image/gif")
+		return "image/gif"
 	default:
-		w.Header().Set("Content-Type", "application/octet-stream")
+		return "application/octet-stream"
 	}
+}
 
-	// Serve file
-	http.ServeFile(w, r, imagePath)
+// fileETag returns a file's content-hash ETag, hashing it again only when its size or modification time changes
+// Content-addressed files are checked against their name as they're hashed, failing with store.ErrImageCorrupt.
+func (h *ImageHandler) fileETag(path string, info os.FileInfo) (string, error) {
+	h.etagMu.Lock()
+	cached, ok := h.etags[path]
+	h.etagMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.etag, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := store.VerifyImage(path, data); err != nil {
+		return "", err
+	}
+	etag := contentETag(data)
+
+	h.etagMu.Lock()
+	h.etags[path] = fileETag{etag: etag, size: info.Size(), modTime: info.ModTime()}
+	h.etagMu.Unlock()
+
+	return etag, nil
+}
+
+// imageCacheControl returns the Cache-Control value for an image file
+// Only content-addressed images are immutable and cached forever. Older images can be rewritten in
+// place by a regeneration, so those named after their date are cached like the date's puzzles and
+// others, such as older bank images, only briefly.
+func imageCacheControl(filename string) string {
+	if _, ok := store.ContentHash(models.OriginalImagePath(filename)); ok {
+		return maxAge(imageMaxAge) + ", immutable"
+	}
+	if len(filename) < 10 || store.ValidateDate(filename[:10]) != nil {
+		return maxAge(todayMaxAge)
+	}
+	return cacheControl(filename[:10])
+}
+
+// acceptsWebP reports whether the request's Accept header lists image/webp with a non-zero quality
+// Wildcards aren't enough: browsers send */* even when they can't decode WebP.
+func acceptsWebP(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			params := strings.Split(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "image/webp") {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "q") {
+					q, err := strconv.ParseFloat(value, 64)
+					return err == nil && q > 0
+				}
+			}
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/s3fake"
+	"backend/internal/store"
+)
+
+const testBucket = "puzzle-images"
+
+var (
+	testPNG  = []byte("\x89PNG test image")
+	testWebP = []byte("RIFF test image")
+)
+
+// newTestStorage returns SupabaseStorage backed by a fake S3 server holding a past day's image and its WebP twin
+func newTestStorage(t *testing.T) (*store.SupabaseStorage, *s3fake.Server) {
+	t.Helper()
+	fake := s3fake.New()
+	t.Cleanup(fake.Close)
+
+	storage, err := store.NewSupabaseStorage(testBucket, "us-east-1", "access-key", "secret-key", fake.URL(), fake.URL()+"/"+testBucket)
+	if err != nil {
+		t.Fatalf("NewSupabaseStorage: %v", err)
+	}
+	if err := storage.SaveObject("2025-01-15/0.png", testPNG); err != nil {
+		t.Fatalf("SaveObject: %v", err)
+	}
+	if err := storage.SaveObject("2025-01-15/0.webp", testWebP); err != nil {
+		t.Fatalf("SaveObject: %v", err)
+	}
+	return storage, fake
+}
+
+// serveImage runs a request for an image through h
+func serveImage(h *ImageHandler, name string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/images/"+name, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeImage(rec, req)
+	return rec
+}
+
+// get fetches a URL and returns its status and body
+func get(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s: %v", url, err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestImageHandlerPublicRedirect(t *testing.T) {
+	_, fake := newTestStorage(t)
+	h := NewImageHandlerWithSupabase(fake.URL() + "/" + testBucket + "/")
+
+	for _, name := range []string{"2025-01-15/0.png", "2025-01-15-0.png"} {
+		rec := serveImage(h, name, nil)
+		if rec.Code != http.StatusFound {
+			t.Fatalf("%s: status = %d, want 302", name, rec.Code)
+		}
+		location := rec.Header().Get("Location")
+		if location != fake.URL()+"/"+testBucket+"/2025-01-15/0.png" {
+			t.Fatalf("%s: Location = %q", name, location)
+		}
+
+		// The bucket serves public-read objects without credentials
+		status, body := get(t, location)
+		if status != http.StatusOK || !bytes.Equal(body, testPNG) {
+			t.Errorf("%s: following the redirect got %d %q", name, status, body)
+		}
+	}
+}
+
+func TestImageHandlerPresignedRedirect(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	storage.SetPrivate([]byte("key secret"))
+	key := "2025-01-15/1.png"
+	if err := storage.SaveObject(key, testPNG); err != nil {
+		t.Fatalf("SaveObject: %v", err)
+	}
+
+	h := NewImageRedirectHandler(storage, 10*time.Minute)
+	rec := serveImage(h, key, nil)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want 302", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "private, max-age=300" {
+		t.Errorf("Cache-Control = %q, want private, max-age=300", got)
+	}
+
+	location := rec.Header().Get("Location")
+	if !strings.Contains(location, "X-Amz-Signature=") {
+		t.Fatalf("Location %q isn't presigned", location)
+	}
+	status, body := get(t, location)
+	if status != http.StatusOK || !bytes.Equal(body, testPNG) {
+		t.Errorf("following the redirect got %d %q", status, body)
+	}
+
+	// The private object itself can't be read without the signature
+	unsigned, _, _ := strings.Cut(location, "?")
+	if status, _ := get(t, unsigned); status != http.StatusForbidden {
+		t.Errorf("unsigned GET = %d, want 403", status)
+	}
+}
+
+func TestImageHandlerProxy(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	h := NewImageProxyHandler(storage, 1<<20)
+
+	rec := serveImage(h, "2025-01-15-0.png", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), testPNG) {
+		t.Errorf("body = %q, want %q", rec.Body.Bytes(), testPNG)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept" {
+		t.Errorf("Vary = %q, want Accept", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=86400" {
+		t.Errorf("Cache-Control = %q, want public, max-age=86400", got)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	// WebP twin for clients that accept it
+	rec = serveImage(h, "2025-01-15/0.png", http.Header{"Accept": {"image/webp,*/*"}})
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), testWebP) {
+		t.Errorf("WebP request got %d %q", rec.Code, rec.Body.Bytes())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/webp" {
+		t.Errorf("WebP Content-Type = %q", got)
+	}
+
+	// Conditional requests
+	rec = serveImage(h, "2025-01-15/0.png", http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match status = %d, want 304", rec.Code)
+	}
+
+	// Past days' images are served from the cache once read
+	if err := storage.DeleteObject("2025-01-15/0.png"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	rec = serveImage(h, "2025-01-15/0.png", nil)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), testPNG) {
+		t.Errorf("cached image got %d %q", rec.Code, rec.Body.Bytes())
+	}
+
+	if rec := serveImage(h, "2025-01-16/0.png", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing image status = %d, want 404", rec.Code)
+	}
+
+	// Bucket errors are 502s
+	fake.Close()
+	if rec := serveImage(h, "2025-01-17/0.png", nil); rec.Code != http.StatusBadGateway {
+		t.Errorf("status with the bucket down = %d, want 502", rec.Code)
+	}
+}
+
+func TestImageHandlerProxyContentAddressed(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	h := NewImageProxyHandler(storage, 1<<20)
+
+	sum := sha256.Sum256(testPNG)
+	key := "sha256/" + hex.EncodeToString(sum[:]) + ".png"
+	if err := storage.SaveObject(key, testPNG); err != nil {
+		t.Fatalf("SaveObject: %v", err)
+	}
+
+	rec := serveImage(h, key, nil)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), testPNG) {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.Bytes())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	// The file system name maps to the same key
+	if rec := serveImage(h, "sha256-"+hex.EncodeToString(sum[:])+".png", nil); rec.Code != http.StatusOK {
+		t.Errorf("file system name status = %d, want 200", rec.Code)
+	}
+
+	// Data that doesn't match its name isn't served
+	other := sha256.Sum256([]byte("other"))
+	corrupt := "sha256/" + hex.EncodeToString(other[:]) + ".png"
+	fake.PutObject(testBucket, corrupt, testPNG, "image/png", "public-read")
+	if rec := serveImage(h, corrupt, nil); rec.Code != http.StatusBadGateway {
+		t.Errorf("corrupt image status = %d, want 502", rec.Code)
+	}
+}
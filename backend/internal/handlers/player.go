@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxPlayerIDLength matches the player_id column size
+const maxPlayerIDLength = 64
+
+// playerIDFromRequest resolves the player ID for a request
+// The explicit value (usually from the JSON body) wins over the X-Player-ID header.
+// Player IDs are chosen by clients and prove nothing, so anything keyed on them, such as the
+// attempt limit, is advisory: a new ID starts afresh. The per-IP verify rate limit is what
+// actually bounds guessing.
+func playerIDFromRequest(r *http.Request, explicit string) (string, error) {
+	playerID := strings.TrimSpace(explicit)
+	if playerID == "" {
+		playerID = strings.TrimSpace(r.Header.Get("X-Player-ID"))
+	}
+
+	if playerID == "" {
+		return "", fmt.Errorf("playerId is required")
+	}
+	if len(playerID) > maxPlayerIDLength {
+		return "", fmt.Errorf("playerId must be at most %d characters", maxPlayerIDLength)
+	}
+
+	return playerID, nil
+}
+
+// playerLocation resolves the player's timezone from the X-Player-Timezone header or tz query parameter
+// Returns nil when the player didn't send one, meaning the publication timezone applies.
+func playerLocation(r *http.Request) (*time.Location, error) {
+	name := strings.TrimSpace(r.Header.Get("X-Player-Timezone"))
+	if name == "" {
+		name = strings.TrimSpace(r.URL.Query().Get("tz"))
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %s", name)
+	}
+	return loc, nil
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// maxImageUploadBytes caps the size of an uploaded puzzle image
+const maxImageUploadBytes = 10 << 20
+
+// maxPuzzleIndex is the highest index a hand-made puzzle can be created at
+const maxPuzzleIndex = 99
+
+// ListPuzzlesHandler handles GET /api/admin/puzzles?date=YYYY-MM-DD
+// Lists every puzzle for a date regardless of review status, with answers
+func (h *AdminHandler) ListPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if err := store.ValidateDate(date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	puzzles, err := h.store.GetPuzzlesForDate(date)
+	if err != nil {
+		http.Error(w, "Failed to list puzzles", http.StatusInternalServerError)
+		return
+	}
+	if puzzles == nil {
+		puzzles = []models.Puzzle{}
+	}
+
+	writeJSON(w, map[string]interface{}{"date": date, "puzzles": puzzles})
+}
+
+// GetPuzzleHandler handles GET /api/admin/puzzles/{id}
+func (h *AdminHandler) GetPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	puzzle, err := h.store.GetPuzzleByID(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, puzzle)
+}
+
+// UpdatePuzzleHandler handles PATCH /api/admin/puzzles/{id}
+// Edits the answer, alternates, hint, explanation, theme or difficulty; omitted fields are unchanged
+func (h *AdminHandler) UpdatePuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	var update models.PuzzleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var validationErr error
+	puzzle, err := h.store.UpdatePuzzle(mux.Vars(r)["id"], models.AuditUpdate, h.auth.Actor(r), func(p *models.Puzzle) error {
+		validationErr = applyPuzzleUpdate(p, update)
+		return validationErr
+	})
+	if validationErr != nil {
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, store.ErrPuzzleNotFound) {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update puzzle", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, puzzle)
+}
+
+// CreatePuzzleHandler handles POST /api/admin/puzzles
+// Takes a multipart form with an "image" file plus date, index, answer, hint and difficulty fields
+// (and optionally explanation, theme and alternateAnswers) and creates a draft puzzle for review
+// Drafts don't count as the day's puzzles, so generation still runs for the date and works around them.
+func (h *AdminHandler) CreatePuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		http.Error(w, "Request must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	imageData, err := readImageUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	date := r.FormValue("date")
+	if err := store.ValidateDate(date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(r.FormValue("index"))
+	if err != nil || index < 0 || index > maxPuzzleIndex {
+		http.Error(w, fmt.Sprintf("index must be a number from 0 to %d", maxPuzzleIndex), http.StatusBadRequest)
+		return
+	}
+
+	puzzle := &models.Puzzle{
+		ID:         fmt.Sprintf("%s-%d", date, index),
+		Date:       date,
+		Index:      index,
+		Difficulty: models.DifficultyMedium,
+		Status:     models.PuzzleDraft,
+	}
+	if err := applyPuzzleUpdate(puzzle, formPuzzleUpdate(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	puzzle.ImageURL, puzzle.ImagePath, puzzle.ImageMeta, err = h.store.SaveImage(imageData)
+	if err != nil {
+		http.Error(w, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.store.CreatePuzzle(puzzle, h.auth.Actor(r))
+	if errors.Is(err, store.ErrPuzzleExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create puzzle", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/admin/puzzles/"+puzzle.ID)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(puzzle); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ReplaceImageHandler handles PUT /api/admin/puzzles/{id}/image
+// Accepts a PNG, JPEG, GIF or WebP either as the "image" field of a multipart form or as the raw request body
+func (h *AdminHandler) ReplaceImageHandler(w http.ResponseWriter, r *http.Request) {
+	puzzleID := mux.Vars(r)["id"]
+
+	imageData, err := readImageUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Check first so that no image is saved for a puzzle that doesn't exist
+	if _, err := h.store.GetPuzzleByID(puzzleID); err != nil {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	imageURL, imagePath, meta, err := h.store.SaveImage(imageData)
+	if err != nil {
+		http.Error(w, "Failed to save image", http.StatusInternalServerError)
+		return
+	}
+
+	puzzle, err := h.store.UpdatePuzzle(puzzleID, models.AuditReplaceImage, h.auth.Actor(r), func(p *models.Puzzle) error {
+		p.ImageURL = imageURL
+		p.ImagePath = imagePath
+		p.ImageMeta = meta
+		return nil
+	})
+	if errors.Is(err, store.ErrPuzzleNotFound) {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update puzzle", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, puzzle)
+}
+
+// ListImageVersionsHandler handles GET /api/admin/puzzles/{id}/images
+// Returns every image the puzzle has used, newest first, marking the one it uses now
+func (h *AdminHandler) ListImageVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.store.ListPuzzleImageVersions(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to load image versions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"versions": versions})
+}
+
+// RollbackImageHandler handles POST /api/admin/puzzles/{id}/image/rollback
+// Points the puzzle back at one of the images listed by ListImageVersionsHandler
+func (h *AdminHandler) RollbackImageHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ImageRollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	puzzle, err := h.store.RollbackPuzzleImage(mux.Vars(r)["id"], req.VersionID, h.auth.Actor(r))
+	if errors.Is(err, store.ErrImageVersionNotFound) || errors.Is(err, store.ErrPuzzleNotFound) {
+		http.Error(w, "Image version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to roll back image", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, puzzle)
+}
+
+// ReorderPuzzlesHandler handles POST /api/admin/puzzles/reorder
+// Renumbers a day's puzzles and rewrites their IDs; the body must list every puzzle of the day in its new order
+func (h *AdminHandler) ReorderPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := store.ValidateDate(req.Date); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	puzzles, err := h.store.ReorderPuzzles(req.Date, req.IDs, h.auth.Actor(r))
+	if errors.Is(err, store.ErrInvalidOrder) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reorder puzzles", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"date": req.Date, "puzzles": puzzles})
+}
+
+// DeletePuzzleHandler handles DELETE /api/admin/puzzles/{id}
+// Also deletes the puzzle's player progress and stats; the image is left for the image GC job
+func (h *AdminHandler) DeletePuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	err := h.store.DeletePuzzle(mux.Vars(r)["id"], h.auth.Actor(r))
+	if errors.Is(err, store.ErrPuzzleNotFound) {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete puzzle", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPuzzleAuditHandler handles GET /api/admin/puzzles/{id}/audit
+// Returns every recorded change to the puzzle, newest first, including changes made before it was deleted
+func (h *AdminHandler) GetPuzzleAuditHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.store.ListPuzzleAudit(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Failed to load audit history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"entries": entries})
+}
+
+// applyPuzzleUpdate validates and applies a partial edit
+func applyPuzzleUpdate(p *models.Puzzle, update models.PuzzleUpdate) error {
+	if update.Answer != nil {
+		answer := models.NormalizeAnswer(*update.Answer)
+		if answer == "" {
+			return fmt.Errorf("answer cannot be empty")
+		}
+		p.Answer = answer
+	}
+	if update.AlternateAnswers != nil {
+		p.AlternateAnswers = *update.AlternateAnswers
+	}
+	if update.Hint != nil {
+		if strings.TrimSpace(*update.Hint) == "" {
+			return fmt.Errorf("hint cannot be empty")
+		}
+		p.Hint = *update.Hint
+	}
+	if update.Explanation != nil {
+		p.Explanation = *update.Explanation
+	}
+	if update.Theme != nil {
+		p.Theme = strings.TrimSpace(*update.Theme)
+	}
+	if update.Difficulty != nil {
+		difficulty := strings.ToLower(strings.TrimSpace(*update.Difficulty))
+		if !models.IsValidDifficulty(difficulty) {
+			return fmt.Errorf("difficulty must be easy, medium or hard")
+		}
+		p.Difficulty = difficulty
+	}
+
+	// Normalize alternates and drop blanks, duplicates and copies of the main answer
+	seen := map[string]bool{p.Answer: true}
+	alternates := []string{}
+	for _, alternate := range p.AlternateAnswers {
+		alternate = models.NormalizeAnswer(alternate)
+		if alternate == "" || seen[alternate] {
+			continue
+		}
+		seen[alternate] = true
+		alternates = append(alternates, alternate)
+	}
+	p.AlternateAnswers = alternates
+
+	return nil
+}
+
+// formPuzzleUpdate reads a puzzle's text fields from a form; alternateAnswers may be repeated or comma-separated
+func formPuzzleUpdate(r *http.Request) models.PuzzleUpdate {
+	answer := r.FormValue("answer")
+	hint := r.FormValue("hint")
+	update := models.PuzzleUpdate{Answer: &answer, Hint: &hint}
+
+	if difficulty := r.FormValue("difficulty"); difficulty != "" {
+		update.Difficulty = &difficulty
+	}
+	if explanation := r.FormValue("explanation"); explanation != "" {
+		update.Explanation = &explanation
+	}
+	if theme := r.FormValue("theme"); theme != "" {
+		update.Theme = &theme
+	}
+
+	var alternates []string
+	for _, value := range r.Form["alternateAnswers"] {
+		alternates = append(alternates, strings.Split(value, ",")...)
+	}
+	update.AlternateAnswers = &alternates
+
+	return update
+}
+
+// readImageUpload reads an image upload from a multipart "image" field or the raw body
+// The image's type and dimensions are checked; the store normalizes it to PNG when saving.
+func readImageUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadBytes)
+
+	var reader io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			return nil, fmt.Errorf("missing image file: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	imageData, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if err := store.ValidateImage(imageData); err != nil {
+		return nil, err
+	}
+
+	return imageData, nil
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,31 +2,63 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"backend/internal/auth"
 	"backend/internal/models"
+	"backend/internal/release"
 	"backend/internal/scheduler"
 	"backend/internal/store"
 )
 
 // PuzzleHandler handles puzzle-related HTTP requests
 type PuzzleHandler struct {
-	store     *store.Store
-	scheduler *scheduler.Scheduler
+	store       *store.Store
+	scheduler   *scheduler.Scheduler
+	policy      *release.Policy
+	auth        *auth.Authenticator
+	maxAttempts int // Maximum guesses per puzzle per player ID (0 = unlimited); advisory, see playerIDFromRequest
 }
 
 // NewPuzzleHandler creates a new puzzle handler
-func NewPuzzleHandler(store *store.Store, sched *scheduler.Scheduler) *PuzzleHandler {
+func NewPuzzleHandler(store *store.Store, sched *scheduler.Scheduler, policy *release.Policy, authenticator *auth.Authenticator, maxAttempts int) *PuzzleHandler {
 	return &PuzzleHandler{
-		store:     store,
-		scheduler: sched,
+		store:       store,
+		scheduler:   sched,
+		policy:      policy,
+		auth:        authenticator,
+		maxAttempts: maxAttempts,
 	}
 }
 
+// isVisible reports whether the caller may see a date's puzzles
+// Any API token can see unreleased dates; everyone else has to wait for the release time in their timezone.
+func (h *PuzzleHandler) isVisible(r *http.Request, date string, playerLoc *time.Location) (bool, error) {
+	visible, _, err := h.visibility(r, date, playerLoc)
+	return visible, err
+}
+
+// visibility reports whether the caller may see a date's puzzles, and whether that's only
+// because the caller has an API token and the date hasn't been released yet
+func (h *PuzzleHandler) visibility(r *http.Request, date string, playerLoc *time.Location) (visible, early bool, err error) {
+	released, err := h.policy.IsReleased(date, time.Now(), playerLoc)
+	if err != nil {
+		return false, false, err
+	}
+	if released {
+		return true, false, nil
+	}
+	if h.auth.HasRole(r, auth.RoleViewer) {
+		return true, true, nil
+	}
+	return false, false, nil
+}
+
 // GetPuzzlesHandler handles GET /api/puzzles/{date}
 func (h *PuzzleHandler) GetPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -38,23 +70,81 @@ func (h *PuzzleHandler) GetPuzzlesHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get puzzles from store
-	puzzles, err := h.store.GetPuzzlesForDate(date)
+	playerLoc, err := playerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writePuzzlesForDate(w, r, date, playerLoc)
+}
+
+// GetTodayPuzzlesHandler handles GET /api/puzzles/today
+// "Today" is the player's local day when X-Player-Timezone (or ?tz=) is sent, otherwise the publication day
+func (h *PuzzleHandler) GetTodayPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	playerLoc, err := playerLocation(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("No puzzles found for date: %s. They may not have been generated yet.", date), http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	h.writePuzzlesForDate(w, r, h.policy.Today(time.Now(), playerLoc), playerLoc)
+}
+
+// writePuzzlesForDate writes the public puzzles for a date, hiding unreleased dates
+func (h *PuzzleHandler) writePuzzlesForDate(w http.ResponseWriter, r *http.Request, date string, playerLoc *time.Location) {
+	notFound := fmt.Sprintf("No puzzles found for date: %s. They may not have been generated yet.", date)
+
+	// Unreleased dates look exactly like dates without puzzles
+	visible, early, err := h.visibility(r, date, playerLoc)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+
+	// Get puzzles from store; puzzles still in review aren't shown
+	puzzles, err := h.store.GetPlayablePuzzlesForDate(date)
+	if err != nil || len(puzzles) == 0 {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+
+	publicPuzzles := make([]models.PublicPuzzle, len(puzzles))
+	for i, p := range puzzles {
+		publicPuzzles[i] = models.NewPublicPuzzle(p)
+	}
+
 	response := models.PuzzlesResponse{
 		Date:    date,
-		Puzzles: puzzles,
+		Puzzles: publicPuzzles,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	body, err := json.Marshal(response)
+	if err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
+
+	lastModified := time.Time{}
+	for _, p := range puzzles {
+		if p.UpdatedAt.After(lastModified) {
+			lastModified = p.UpdatedAt
+		}
+	}
+
+	// Release times depend on the player's timezone, and unreleased puzzles mustn't reach shared caches
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Vary", "X-Player-Timezone")
+	if early {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", cacheControl(date))
+	}
+	serveCached(w, r, append(body, '\n'), lastModified)
 }
 
 // VerifyAnswerHandler handles POST /api/puzzles/verify
@@ -65,6 +155,12 @@ func (h *PuzzleHandler) VerifyAnswerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	playerID, err := playerIDFromRequest(r, req.PlayerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Parse puzzle ID to get date and index
 	date, _, err := store.ParsePuzzleID(req.PuzzleID)
 	if err != nil {
@@ -72,8 +168,24 @@ func (h *PuzzleHandler) VerifyAnswerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	playerLoc, err := playerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visible, err := h.isVisible(r, date, playerLoc)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
 	// Get puzzles for the date
-	puzzles, err := h.store.GetPuzzlesForDate(date)
+	puzzles, err := h.store.GetPlayablePuzzlesForDate(date)
 	if err != nil {
 		http.Error(w, "Puzzle not found", http.StatusNotFound)
 		return
@@ -93,13 +205,28 @@ func (h *PuzzleHandler) VerifyAnswerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Compare answers (case-insensitive, trimmed)
-	userAnswer := strings.ToLower(strings.TrimSpace(req.Answer))
-	correctAnswer := strings.ToLower(strings.TrimSpace(puzzle.Answer))
-	correct := userAnswer == correctAnswer
+	// Compare answers (case-insensitive, trimmed), accepting alternates
+	correct := puzzle.Accepts(req.Answer)
+
+	progress, recorded, err := h.store.RecordAttempt(playerID, puzzle.ID, correct, h.maxAttempts)
+	if err != nil {
+		http.Error(w, "Failed to record attempt", http.StatusInternalServerError)
+		return
+	}
+
+	// Guesses after giving up or running out of attempts don't count
+	if !recorded && progress.Status == models.ProgressFailed {
+		http.Error(w, "No attempts remaining for this puzzle", http.StatusForbidden)
+		return
+	}
 
 	response := models.VerifyResponse{
-		Correct: correct,
+		Correct:           correct,
+		Status:            progress.Status,
+		RemainingAttempts: h.remainingAttempts(progress),
+	}
+	if progress.IsFinished() {
+		response.Answer = puzzle.Answer
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -109,10 +236,163 @@ func (h *PuzzleHandler) VerifyAnswerHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// GiveUpHandler handles POST /api/puzzles/{id}/giveup
+// Marks the puzzle as failed for the player and reveals the answer
+func (h *PuzzleHandler) GiveUpHandler(w http.ResponseWriter, r *http.Request) {
+	puzzleID := mux.Vars(r)["id"]
+
+	var req models.GiveUpRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	playerID, err := playerIDFromRequest(r, req.PlayerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	date, _, err := store.ParsePuzzleID(puzzleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playerLoc, err := playerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visible, err := h.isVisible(r, date, playerLoc)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	puzzle, err := h.store.GetPuzzleByID(puzzleID)
+	if err != nil || !puzzle.IsPlayable() {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	progress, err := h.store.MarkPuzzleFailed(playerID, puzzle.ID)
+	if err != nil {
+		http.Error(w, "Failed to give up puzzle", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.GiveUpResponse{
+		PuzzleID: puzzle.ID,
+		Answer:   puzzle.Answer,
+		Status:   progress.Status,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// latestTimezone is where a date ends last
+var latestTimezone = time.FixedZone("UTC-12", -12*60*60)
+
+// SolutionHandler handles GET /api/puzzles/{id}/solution
+// The solution is only revealed once the puzzle's day is over or the player has solved or given up on it
+func (h *PuzzleHandler) SolutionHandler(w http.ResponseWriter, r *http.Request) {
+	puzzleID := mux.Vars(r)["id"]
+
+	date, _, err := store.ParsePuzzleID(puzzleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playerLoc, err := playerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	visible, err := h.isVisible(r, date, playerLoc)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	puzzle, err := h.store.GetPuzzleByID(puzzleID)
+	if err != nil || !puzzle.IsPlayable() {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	// Dates are YYYY-MM-DD so string comparison orders them chronologically
+	// The day has to be over everywhere, so that no client timezone can bring the answer forward.
+	revealed := date < h.policy.Today(time.Now(), latestTimezone)
+	if !revealed {
+		// The player is optional here; without one only past puzzles are revealed
+		if playerID, err := playerIDFromRequest(r, r.URL.Query().Get("playerId")); err == nil {
+			progress, err := h.store.GetPlayerProgress(playerID, puzzle.ID)
+			if err != nil {
+				http.Error(w, "Failed to load player progress", http.StatusInternalServerError)
+				return
+			}
+			revealed = progress.IsFinished()
+		}
+	}
+
+	if !revealed {
+		http.Error(w, "Solution is not available until the puzzle is over", http.StatusForbidden)
+		return
+	}
+
+	response := models.SolutionResponse{
+		PuzzleID:    puzzle.ID,
+		Answer:      puzzle.Answer,
+		Hint:        puzzle.Hint,
+		Explanation: puzzle.Explanation,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// remainingAttempts returns how many guesses the player has left, or nil when unlimited
+func (h *PuzzleHandler) remainingAttempts(progress *models.PlayerProgress) *int {
+	if h.maxAttempts <= 0 {
+		return nil
+	}
+
+	remaining := h.maxAttempts - progress.Attempts
+	if remaining < 0 || progress.IsFinished() {
+		remaining = 0
+	}
+	return &remaining
+}
+
 // TriggerJobHandler handles POST /api/puzzles/trigger
 // Triggers puzzle generation for today if puzzles don't exist
 func (h *PuzzleHandler) TriggerJobHandler(w http.ResponseWriter, r *http.Request) {
 	generated, err := h.scheduler.TriggerTodayGeneration()
+	if errors.Is(err, scheduler.ErrGenerationInProgress) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to trigger job: %v", err), http.StatusInternalServerError)
 		return
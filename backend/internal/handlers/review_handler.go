@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// ListPendingPuzzlesHandler handles GET /api/admin/puzzles/pending
+// Lists draft and in-review puzzles with their answers and image URLs so they can be reviewed
+func (h *AdminHandler) ListPendingPuzzlesHandler(w http.ResponseWriter, r *http.Request) {
+	puzzles, err := h.store.ListPendingPuzzles()
+	if err != nil {
+		http.Error(w, "Failed to list pending puzzles", http.StatusInternalServerError)
+		return
+	}
+	if puzzles == nil {
+		puzzles = []models.Puzzle{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"puzzles": puzzles}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ApprovePuzzleHandler handles POST /api/admin/puzzles/{id}/approve
+// Approved puzzles are shown to players once their date is released
+func (h *AdminHandler) ApprovePuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	h.reviewPuzzle(w, r, models.PuzzleApproved, []string{models.PuzzleDraft, models.PuzzleInReview, models.PuzzleRejected})
+}
+
+// RejectPuzzleHandler handles POST /api/admin/puzzles/{id}/reject
+// Published puzzles, and approved ones whose date has been released anywhere, can't be rejected,
+// since players may already have played them (the publish job only runs every few minutes)
+func (h *AdminHandler) RejectPuzzleHandler(w http.ResponseWriter, r *http.Request) {
+	puzzle, err := h.store.GetPuzzleByID(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+
+	released, err := h.policy.IsReleased(puzzle.Date, time.Now(), earliestTimezone)
+	if err != nil {
+		http.Error(w, "Failed to check release time", http.StatusInternalServerError)
+		return
+	}
+
+	from := []string{models.PuzzleDraft, models.PuzzleInReview, models.PuzzleApproved}
+	if released {
+		from = []string{models.PuzzleDraft, models.PuzzleInReview}
+	}
+	h.reviewPuzzle(w, r, models.PuzzleRejected, from)
+}
+
+// reviewPuzzle moves a puzzle to status if it's currently in one of from, recording the reviewer's notes
+func (h *AdminHandler) reviewPuzzle(w http.ResponseWriter, r *http.Request, status string, from []string) {
+	puzzleID := mux.Vars(r)["id"]
+
+	var req models.ReviewRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	puzzle, err := h.store.ReviewPuzzle(puzzleID, status, req.Notes, h.auth.Actor(r), from)
+	if errors.Is(err, store.ErrPuzzleNotFound) {
+		http.Error(w, "Puzzle not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to review puzzle", http.StatusInternalServerError)
+		return
+	}
+	if puzzle == nil {
+		http.Error(w, "Puzzle status does not allow changing it to "+status, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(puzzle); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// ListTokensHandler handles GET /api/admin/tokens
+// Lists every API token, including revoked ones; token secrets are never returned
+func (h *AdminHandler) ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.store.ListAPITokens()
+	if err != nil {
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"tokens": tokens})
+}
+
+// CreateTokenHandler handles POST /api/admin/tokens
+// The response is the only time the token itself is shown
+func (h *AdminHandler) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if !auth.IsValidRole(req.Role) {
+		http.Error(w, "role must be viewer, editor or admin", http.StatusBadRequest)
+		return
+	}
+
+	token, tokenHash, err := auth.GenerateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	stored, err := h.store.CreateAPIToken(req.Name, req.Role, tokenHash, h.auth.Actor(r))
+	if err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(models.CreateTokenResponse{APIToken: *stored, Token: token}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RevokeTokenHandler handles DELETE /api/admin/tokens/{id}
+func (h *AdminHandler) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.store.RevokeAPIToken(id)
+	if err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
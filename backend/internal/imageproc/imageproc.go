@@ -0,0 +1,200 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register the GIF decoder
+	_ "image/jpeg" // Register the JPEG decoder
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register the WebP decoder; Replicate models often return WebP
+)
+
+// maxSourcePixels rejects images whose header claims more pixels than is sane to decode
+const maxSourcePixels = 40_000_000
+
+// Errors returned for images that can't be used as puzzles
+var (
+	ErrEmpty   = errors.New("image is empty")
+	ErrTooBig  = errors.New("image dimensions are too large")
+	ErrBlank   = errors.New("image has no visible content")
+	ErrDecode  = errors.New("image could not be decoded")
+	ErrUnknown = errors.New("image format is not supported")
+)
+
+// Options controls how an image is normalized
+type Options struct {
+	Width     int   // Output width; 0 with Height 0 keeps the source size
+	Height    int   // Output height
+	Threshold uint8 // Luminance above which a pixel becomes white foreground; 0 keeps the colours
+	Softness  uint8 // Width of the grey ramp either side of Threshold, which keeps edges anti-aliased
+}
+
+// DefaultOptions matches what the image model is asked for: 800x600, white elements on black
+func DefaultOptions() Options {
+	return Options{
+		Width:     800,
+		Height:    600,
+		Threshold: 128,
+		Softness:  24,
+	}
+}
+
+// Result is a processed image
+type Result struct {
+	Data          []byte // Canonical PNG
+	Width         int
+	Height        int
+	SourceFormat  string  // Format the image arrived in, e.g. "webp"
+	Inverted      bool    // The source was dark on light and was inverted
+	Foreground    float64 // Fraction of pixels that are foreground after thresholding
+	BlurHash      string  // Placeholder for clients to show while the image loads
+	DominantColor string  // Most common colour, as #rrggbb
+}
+
+// Process decodes an image, checks it, and re-encodes it as the canonical PNG that gets stored
+// The image is scaled to fit Width x Height. With a Threshold it is reduced to white on black, and
+// images that come out mostly white are assumed to be dark-on-light and inverted. It is then centred
+// on a black canvas. Re-encoding from pixels drops any metadata the source carried.
+func Process(data []byte, opts Options) (*Result, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknown, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooBig, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+
+	scaled := scale(src, opts.Width, opts.Height)
+	result := &Result{SourceFormat: format}
+
+	var content draw.Image = scaled
+	if opts.Threshold > 0 {
+		gray, foreground := threshold(scaled, opts.Threshold, opts.Softness)
+		if foreground > 0.6 {
+			invert(gray)
+			foreground = 1 - foreground
+			result.Inverted = true
+		}
+		if foreground < 0.001 {
+			return nil, ErrBlank
+		}
+		result.Foreground = foreground
+		content = gray
+	}
+
+	out := pad(content, opts.Width, opts.Height)
+	result.Width, result.Height = out.Bounds().Dx(), out.Bounds().Dy()
+	result.BlurHash = BlurHash(out)
+	result.DominantColor = DominantColor(out)
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	result.Data = buf.Bytes()
+
+	return result, nil
+}
+
+// scale resizes src to fit inside width x height, keeping its aspect ratio
+// Transparent areas become black. A zero size keeps the source dimensions.
+func scale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	if width <= 0 || height <= 0 {
+		width, height = bounds.Dx(), bounds.Dy()
+	}
+
+	// Use the tighter of the two ratios so the whole image fits
+	scaledW, scaledH := width, max(1, bounds.Dy()*width/bounds.Dx())
+	if scaledH > height {
+		scaledW, scaledH = max(1, bounds.Dx()*height/bounds.Dy()), height
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, scaledW, scaledH))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if dst.Bounds().Size() == bounds.Size() {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	}
+
+	return dst
+}
+
+// pad centres content on a black width x height canvas of the same colour model
+// A zero size returns content unchanged.
+func pad(content draw.Image, width, height int) draw.Image {
+	bounds := content.Bounds()
+	if width <= 0 || height <= 0 || (bounds.Dx() == width && bounds.Dy() == height) {
+		return content
+	}
+
+	var canvas draw.Image
+	if _, ok := content.(*image.Gray); ok {
+		canvas = image.NewGray(image.Rect(0, 0, width, height))
+	} else {
+		canvas = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	offset := image.Pt((width-bounds.Dx())/2, (height-bounds.Dy())/2)
+	draw.Draw(canvas, bounds.Sub(bounds.Min).Add(offset), content, bounds.Min, draw.Src)
+
+	return canvas
+}
+
+// threshold converts an image to greyscale, pushing pixels to black or white around cutoff
+// Pixels within softness of the cutoff are ramped linearly so edges stay smooth.
+// Returns the image and the fraction of pixels at or above the cutoff.
+func threshold(src *image.RGBA, cutoff, softness uint8) (*image.Gray, float64) {
+	bounds := src.Bounds()
+	gray := image.NewGray(bounds)
+
+	low := int(cutoff) - int(softness)
+	high := int(cutoff) + int(softness)
+	foreground := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			lum := int(color.GrayModel.Convert(src.RGBAAt(x, y)).(color.Gray).Y)
+			if lum >= int(cutoff) {
+				foreground++
+			}
+
+			var v int
+			switch {
+			case lum <= low:
+				v = 0
+			case lum >= high:
+				v = 255
+			default:
+				v = (lum - low) * 255 / (high - low)
+			}
+			gray.Pix[gray.PixOffset(x, y)] = uint8(v)
+		}
+	}
+
+	return gray, float64(foreground) / float64(bounds.Dx()*bounds.Dy())
+}
+
+// invert flips a greyscale image in place
+func invert(img *image.Gray) {
+	for i, v := range img.Pix {
+		img.Pix[i] = 255 - v
+	}
+}
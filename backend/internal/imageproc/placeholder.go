@@ -0,0 +1,167 @@
+package imageproc
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// BlurHash components across and down; 4x3 matches the 4:3 puzzle images
+const (
+	blurHashX = 4
+	blurHashY = 3
+)
+
+// base83 is BlurHash's alphabet
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// BlurHash encodes a tiny blurred version of img that clients can render while the image loads
+// See https://github.com/woltapp/blurhash for the format.
+func BlurHash(img image.Image) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Cosine bases for each component along each axis, computed once rather than per pixel
+	cosX := make([][]float64, blurHashX)
+	for i := range cosX {
+		cosX[i] = make([]float64, width)
+		for x := range cosX[i] {
+			cosX[i][x] = math.Cos(math.Pi * float64(i) * float64(x) / float64(width))
+		}
+	}
+	cosY := make([][]float64, blurHashY)
+	for j := range cosY {
+		cosY[j] = make([]float64, height)
+		for y := range cosY[j] {
+			cosY[j][y] = math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+		}
+	}
+
+	var factors [blurHashX * blurHashY][3]float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			linear := [3]float64{srgbToLinear(r >> 8), srgbToLinear(g >> 8), srgbToLinear(b >> 8)}
+			for j := 0; j < blurHashY; j++ {
+				for i := 0; i < blurHashX; i++ {
+					basis := cosX[i][x] * cosY[j][y]
+					f := &factors[j*blurHashX+i]
+					f[0] += basis * linear[0]
+					f[1] += basis * linear[1]
+					f[2] += basis * linear[2]
+				}
+			}
+		}
+	}
+
+	pixels := float64(width * height)
+	for k := range factors {
+		normalisation := 2.0
+		if k == 0 {
+			normalisation = 1
+		}
+		for c := range factors[k] {
+			factors[k][c] *= normalisation / pixels
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((blurHashX-1)+(blurHashY-1)*9, 1))
+
+	maxAC := 0.0
+	for _, f := range factors[1:] {
+		for _, v := range f {
+			maxAC = math.Max(maxAC, math.Abs(v))
+		}
+	}
+	quantisedMax := clampInt(int(math.Floor(maxAC*166-0.5)), 0, 82)
+	maxValue := float64(quantisedMax+1) / 166
+	hash.WriteString(encode83(quantisedMax, 1))
+
+	dc := factors[0]
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+
+	for _, f := range factors[1:] {
+		var q [3]int
+		for c, v := range f {
+			q[c] = clampInt(int(math.Floor(signPow(v/maxValue, 0.5)*9+9.5)), 0, 18)
+		}
+		hash.WriteString(encode83(q[0]*19*19+q[1]*19+q[2], 2))
+	}
+
+	return hash.String()
+}
+
+// DominantColor returns the most common colour in img as #rrggbb
+// Colours are bucketed to 4 bits a channel so anti-aliasing doesn't split a flat area across buckets,
+// and the winning bucket's pixels are averaged.
+func DominantColor(img image.Image) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	var buckets [1 << 12]bucket
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			r, g, b = r>>8, g>>8, b>>8
+			bk := &buckets[r>>4<<8|g>>4<<4|b>>4]
+			bk.count++
+			bk.r += int(r)
+			bk.g += int(g)
+			bk.b += int(b)
+		}
+	}
+
+	best := &buckets[0]
+	for i := range buckets {
+		if buckets[i].count > best.count {
+			best = &buckets[i]
+		}
+	}
+	if best.count == 0 {
+		return "#000000"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
+
+// encode83 writes value as length base 83 digits, most significant first
+func encode83(value, length int) string {
+	digits := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		digits[i] = base83[value%83]
+		value /= 83
+	}
+	return string(digits)
+}
+
+// srgbToLinear converts an 8-bit sRGB channel to linear light
+func srgbToLinear(v uint32) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light back to an 8-bit sRGB channel
+func linearToSRGB(v float64) int {
+	c := math.Max(0, math.Min(1, v))
+	if c <= 0.0031308 {
+		return int(c*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(c, 1/2.4)-0.055)*255 + 0.5)
+}
+
+// signPow raises |v| to exp, keeping v's sign
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// clampInt limits v to [low, high]
+func clampInt(v, low, high int) int {
+	return max(low, min(high, v))
+}
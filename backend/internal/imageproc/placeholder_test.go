@@ -0,0 +1,79 @@
+package imageproc
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// fill returns a width x height image coloured by f
+func fill(width, height int, f func(x, y int) color.NRGBA) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, f(x, y))
+		}
+	}
+	return img
+}
+
+// TestBlurHash compares against hashes from the reference algorithm with 4x3 components
+func TestBlurHash(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{
+			"black",
+			fill(8, 6, func(x, y int) color.NRGBA { return color.NRGBA{A: 0xff} }),
+			"L00000fQfQfQfQfQfQfQfQfQfQfQ",
+		},
+		{
+			"gradient",
+			fill(32, 24, func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(x * 8), G: uint8(y * 10), B: uint8((x + y) * 4), A: 0xff}
+			}),
+			"LxH27b2kwzX5mAWYjuf7gKfkfQfj",
+		},
+		{
+			"split",
+			fill(40, 30, func(x, y int) color.NRGBA {
+				if x < 20 {
+					return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+				}
+				return color.NRGBA{A: 0xff}
+			}),
+			"L~Lqe9~qt7IUofofj[ayfQfQfQfQ",
+		},
+		{
+			"disc",
+			fill(64, 48, func(x, y int) color.NRGBA {
+				if (x-32)*(x-32)+(y-24)*(y-24) < 15*15 {
+					return color.NRGBA{R: 255, G: 200, A: 0xff}
+				}
+				return color.NRGBA{R: 10, G: 20, B: 90, A: 0xff}
+			}),
+			"LuFEllfR0ofRfRfQfQfQ5FfQ=?fQ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BlurHash(tt.img); got != tt.want {
+				t.Errorf("BlurHash = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlurHashSubImage(t *testing.T) {
+	img := fill(50, 40, func(x, y int) color.NRGBA {
+		return color.NRGBA{R: uint8(x * 5), G: uint8(y * 6), B: uint8(x * y), A: 0xff}
+	}).(*image.NRGBA)
+	sub := img.SubImage(image.Rect(10, 5, 42, 29))
+
+	copied := fill(32, 24, func(x, y int) color.NRGBA { return img.NRGBAAt(x+10, y+5) })
+	if got, want := BlurHash(sub), BlurHash(copied); got != want {
+		t.Errorf("BlurHash of a sub-image = %q, want %q", got, want)
+	}
+}
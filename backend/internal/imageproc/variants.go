@@ -0,0 +1,83 @@
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// Variant is a size an image is also stored at
+type Variant struct {
+	Name  string
+	Width int // 0 keeps the canonical size; height follows the aspect ratio
+}
+
+// DefaultVariants are the sizes clients pick between with srcset
+var DefaultVariants = []Variant{
+	{Name: "thumb", Width: 200},
+	{Name: "mobile", Width: 400},
+	{Name: "full"},
+}
+
+// Rendition is one variant of an image, encoded as PNG and lossless WebP
+type Rendition struct {
+	Name   string
+	Width  int
+	Height int
+	PNG    []byte
+	WebP   []byte
+}
+
+// Renditions renders the variants of a canonical PNG produced by Process
+// Variants at least as wide as the canonical image reuse it unscaled, so the full variant's PNG
+// is the canonical data itself.
+func Renditions(canonical []byte, variants []Variant) ([]Rendition, error) {
+	src, err := png.Decode(bytes.NewReader(canonical))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	bounds := src.Bounds()
+
+	renditions := make([]Rendition, 0, len(variants))
+	for _, v := range variants {
+		img, data := src, canonical
+		if v.Width > 0 && v.Width < bounds.Dx() {
+			height := max(1, bounds.Dy()*v.Width/bounds.Dx())
+			scaled := image.NewRGBA(image.Rect(0, 0, v.Width, height))
+			draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Src, nil)
+
+			var small draw.Image = scaled
+			if _, ok := src.(*image.Gray); ok {
+				// Keep greyscale images greyscale so the PNGs stay small
+				gray := image.NewGray(scaled.Bounds())
+				draw.Draw(gray, gray.Bounds(), scaled, image.Point{}, draw.Src)
+				small = gray
+			}
+
+			var buf bytes.Buffer
+			encoder := png.Encoder{CompressionLevel: png.BestCompression}
+			if err := encoder.Encode(&buf, small); err != nil {
+				return nil, fmt.Errorf("failed to encode %s PNG: %w", v.Name, err)
+			}
+			img, data = small, buf.Bytes()
+		}
+
+		var webp bytes.Buffer
+		if err := EncodeWebP(&webp, img); err != nil {
+			return nil, fmt.Errorf("failed to encode %s WebP: %w", v.Name, err)
+		}
+
+		renditions = append(renditions, Rendition{
+			Name:   v.Name,
+			Width:  img.Bounds().Dx(),
+			Height: img.Bounds().Dy(),
+			PNG:    data,
+			WebP:   webp.Bytes(),
+		})
+	}
+
+	return renditions, nil
+}
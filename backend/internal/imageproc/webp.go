@@ -0,0 +1,423 @@
+package imageproc
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// This file implements a small lossless WebP (VP8L) encoder. It uses the subtract-green transform
+// and greedy run-length backward references, which suits the flat white-on-black puzzle images;
+// it doesn't attempt the predictor, colour or palette transforms a full encoder would.
+// See https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+
+// VP8L limits
+const (
+	vp8lMaxSize       = 1 << 14 // Widths and heights are stored in 14 bits
+	vp8lMaxCodeLength = 15
+	vp8lLengthCodes   = 24
+	vp8lDistanceCodes = 40
+	vp8lMaxCopy       = 4096
+	vp8lMinCopy       = 3 // Shorter runs cost more as a backward reference than as literals
+)
+
+// vp8lCodeLengthOrder is the order code length code lengths are written in
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP writes img as a lossless WebP
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return fmt.Errorf("cannot encode a %dx%d image as WebP", width, height)
+	}
+
+	// ARGB pixels with green subtracted from red and blue
+	pixels := make([]uint32, 0, width*height)
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				opaque = false
+			}
+			r, b := c.R-c.G, c.B-c.G
+			pixels = append(pixels, uint32(c.A)<<24|uint32(r)<<16|uint32(c.G)<<8|uint32(b))
+		}
+	}
+
+	tokens := vp8lTokenize(pixels, width)
+
+	// Symbol frequencies for the five prefix codes: green/length, red, blue, alpha, distance
+	green := make([]int, 256+vp8lLengthCodes)
+	red := make([]int, 256)
+	blue := make([]int, 256)
+	alpha := make([]int, 256)
+	distance := make([]int, vp8lDistanceCodes)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.argb>>8&0xff]++
+			red[t.argb>>16&0xff]++
+			blue[t.argb&0xff]++
+			alpha[t.argb>>24]++
+			continue
+		}
+		lengthCode, _, _ := vp8lPrefix(t.length)
+		distanceCode, _, _ := vp8lPrefix(t.distanceCode)
+		green[256+lengthCode]++
+		distance[distanceCode]++
+	}
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8) // VP8L signature
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if opaque {
+		bw.writeBits(0, 1)
+	} else {
+		bw.writeBits(1, 1)
+	}
+	bw.writeBits(0, 3) // Version
+
+	bw.writeBits(1, 1) // A transform follows
+	bw.writeBits(2, 2) // SUBTRACT_GREEN
+	bw.writeBits(0, 1) // No more transforms
+
+	bw.writeBits(0, 1) // No colour cache
+	bw.writeBits(0, 1) // One prefix code group for the whole image
+
+	codes := make([]prefixCode, 5)
+	for i, freqs := range [][]int{green, red, blue, alpha, distance} {
+		codes[i] = writePrefixCode(bw, freqs)
+	}
+	greenCode, redCode, blueCode, alphaCode, distanceCode := codes[0], codes[1], codes[2], codes[3], codes[4]
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			greenCode.write(bw, int(t.argb>>8&0xff))
+			redCode.write(bw, int(t.argb>>16&0xff))
+			blueCode.write(bw, int(t.argb&0xff))
+			alphaCode.write(bw, int(t.argb>>24))
+			continue
+		}
+		code, extraBits, extra := vp8lPrefix(t.length)
+		greenCode.write(bw, 256+code)
+		bw.writeBits(extra, extraBits)
+		code, extraBits, extra = vp8lPrefix(t.distanceCode)
+		distanceCode.write(bw, code)
+		bw.writeBits(extra, extraBits)
+	}
+
+	data := bw.bytes()
+	chunkSize := len(data)
+	padded := chunkSize + chunkSize&1
+
+	var header [20]byte
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+padded))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(chunkSize))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padded != chunkSize {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vp8lToken is a literal pixel (length 0) or a backward reference
+type vp8lToken struct {
+	argb         uint32
+	length       int
+	distanceCode int // Distance plane code: 1 is the pixel above, 2 the pixel to the left
+}
+
+// vp8lTokenize greedily replaces runs that repeat the previous pixel or the row above with backward references
+func vp8lTokenize(pixels []uint32, width int) []vp8lToken {
+	tokens := make([]vp8lToken, 0, len(pixels)/8)
+	for i := 0; i < len(pixels); {
+		leftRun, aboveRun := 0, 0
+		if i >= 1 {
+			for leftRun < vp8lMaxCopy && i+leftRun < len(pixels) && pixels[i+leftRun] == pixels[i+leftRun-1] {
+				leftRun++
+			}
+		}
+		if i >= width {
+			for aboveRun < vp8lMaxCopy && i+aboveRun < len(pixels) && pixels[i+aboveRun] == pixels[i+aboveRun-width] {
+				aboveRun++
+			}
+		}
+
+		switch {
+		case aboveRun >= vp8lMinCopy && aboveRun >= leftRun:
+			tokens = append(tokens, vp8lToken{length: aboveRun, distanceCode: 1})
+			i += aboveRun
+		case leftRun >= vp8lMinCopy:
+			tokens = append(tokens, vp8lToken{length: leftRun, distanceCode: 2})
+			i += leftRun
+		default:
+			tokens = append(tokens, vp8lToken{argb: pixels[i]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// vp8lPrefix splits a length or distance into its prefix code and extra bits
+func vp8lPrefix(value int) (code int, extraBits uint, extra uint32) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	highBit := 0
+	for d>>(highBit+1) != 0 {
+		highBit++
+	}
+	secondBit := d >> (highBit - 1) & 1
+	extraBits = uint(highBit - 1)
+	return 2*highBit + secondBit, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// prefixCode is a canonical Huffman code ready for writing
+type prefixCode struct {
+	lengths []int
+	codes   []uint32 // Bit-reversed, since the bitstream is read least significant bit first
+}
+
+// write emits a symbol's code; symbols of a single-symbol code take no bits
+func (c prefixCode) write(bw *bitWriter, symbol int) {
+	bw.writeBits(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode writes the code for a set of symbol frequencies and returns it
+func writePrefixCode(bw *bitWriter, freqs []int) prefixCode {
+	var used []int
+	for symbol, freq := range freqs {
+		if freq > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// A single symbol below 256 can use the simple form, which gives it a zero-length code
+	if len(used) <= 1 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		if symbol < 256 {
+			bw.writeBits(1, 1) // Simple code
+			bw.writeBits(0, 1) // One symbol
+			if symbol < 2 {
+				bw.writeBits(0, 1)
+				bw.writeBits(uint32(symbol), 1)
+			} else {
+				bw.writeBits(1, 1)
+				bw.writeBits(uint32(symbol), 8)
+			}
+			return prefixCode{lengths: make([]int, len(freqs)), codes: make([]uint32, len(freqs))}
+		}
+	}
+
+	// Normal codes need at least two symbols to form a complete tree
+	freqs = append([]int(nil), freqs...)
+	for symbol := 0; len(used) < 2; symbol++ {
+		if freqs[symbol] == 0 {
+			freqs[symbol] = 1
+			used = append(used, symbol)
+		}
+	}
+
+	lengths := huffmanLengths(freqs, vp8lMaxCodeLength)
+
+	// The code lengths themselves are written with a code length code, one token per length
+	lengthFreqs := make([]int, 19)
+	for _, length := range lengths {
+		lengthFreqs[length]++
+	}
+	for distinct := 0; distinct < 2; {
+		distinct = 0
+		for _, f := range lengthFreqs {
+			if f > 0 {
+				distinct++
+			}
+		}
+		if distinct < 2 {
+			// Pad with an unused token so the code length code is a complete tree too
+			if lengthFreqs[0] == 0 {
+				lengthFreqs[0] = 1
+			} else {
+				lengthFreqs[1] = 1
+			}
+		}
+	}
+	lengthLengths := huffmanLengths(lengthFreqs, 7)
+	lengthCode := canonicalCode(lengthLengths)
+
+	bw.writeBits(0, 1)    // Normal code
+	bw.writeBits(19-4, 4) // All 19 code length code lengths follow
+	for _, symbol := range vp8lCodeLengthOrder {
+		bw.writeBits(uint32(lengthLengths[symbol]), 3)
+	}
+	bw.writeBits(0, 1) // Code lengths for every symbol follow
+	for _, length := range lengths {
+		lengthCode.write(bw, length)
+	}
+
+	return canonicalCode(lengths)
+}
+
+// canonicalCode assigns canonical Huffman codes to code lengths
+func canonicalCode(lengths []int) prefixCode {
+	var count [vp8lMaxCodeLength + 1]int
+	for _, length := range lengths {
+		count[length]++
+	}
+	count[0] = 0
+
+	var next [vp8lMaxCodeLength + 2]uint32
+	code := uint32(0)
+	for bits := 1; bits <= vp8lMaxCodeLength; bits++ {
+		code = (code + uint32(count[bits-1])) << 1
+		next[bits] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		codes[symbol] = reverseBits(next[length], length)
+		next[length]++
+	}
+	return prefixCode{lengths: lengths, codes: codes}
+}
+
+// reverseBits reverses the low n bits of v
+func reverseBits(v uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// huffmanLengths computes Huffman code lengths no longer than maxLength
+// Frequencies are flattened and the tree rebuilt until it fits, as libwebp does.
+func huffmanLengths(freqs []int, maxLength int) []int {
+	scaled := append([]int(nil), freqs...)
+	for {
+		lengths := huffmanTree(scaled)
+		longest := 0
+		for _, length := range lengths {
+			if length > longest {
+				longest = length
+			}
+		}
+		if longest <= maxLength {
+			return lengths
+		}
+		for i, f := range scaled {
+			if f > 0 {
+				scaled[i] = f/2 + 1
+			}
+		}
+	}
+}
+
+// huffmanNode is a node in the tree built by huffmanTree
+type huffmanNode struct {
+	freq        int
+	symbol      int // -1 for internal nodes
+	left, right *huffmanNode
+}
+
+// huffmanHeap orders nodes by frequency, then symbol, so trees are deterministic
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	node := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return node
+}
+
+// huffmanTree returns unlimited Huffman code lengths for the symbols with non-zero frequency
+func huffmanTree(freqs []int) []int {
+	h := &huffmanHeap{}
+	for symbol, freq := range freqs {
+		if freq > 0 {
+			*h = append(*h, &huffmanNode{freq: freq, symbol: symbol})
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{freq: a.freq + b.freq, symbol: -1, left: a, right: b})
+	}
+
+	lengths := make([]int, len(freqs))
+	var walk func(n *huffmanNode, depth int)
+	walk = func(n *huffmanNode, depth int) {
+		if n.symbol >= 0 {
+			lengths[n.symbol] = depth
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	if h.Len() == 1 {
+		walk((*h)[0], 0)
+	}
+	return lengths
+}
+
+// bitWriter packs bits least significant first
+type bitWriter struct {
+	buf   bytes.Buffer
+	acc   uint64
+	nbits uint
+}
+
+// writeBits appends the low n bits of v
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf.WriteByte(byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+// bytes flushes any partial byte and returns everything written
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf.WriteByte(byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf.Bytes()
+}
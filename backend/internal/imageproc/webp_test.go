@@ -0,0 +1,161 @@
+package imageproc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// roundTrip encodes img as WebP, decodes it with x/image/webp and checks every pixel survived
+func roundTrip(t *testing.T, img image.Image) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img); err != nil {
+		t.Fatalf("EncodeWebP: %v", err)
+	}
+	if buf.Len()%2 != 0 {
+		t.Errorf("RIFF data is %d bytes, want an even length", buf.Len())
+	}
+
+	decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("webp.Decode: %v", err)
+	}
+
+	bounds := img.Bounds()
+	if decoded.Bounds().Dx() != bounds.Dx() || decoded.Bounds().Dy() != bounds.Dy() {
+		t.Fatalf("decoded size %v, want %dx%d", decoded.Bounds().Size(), bounds.Dx(), bounds.Dy())
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			want := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			got := color.NRGBAModel.Convert(decoded.At(decoded.Bounds().Min.X+x, decoded.Bounds().Min.Y+y)).(color.NRGBA)
+			if want.A == 0 {
+				// Fully transparent pixels only need to stay transparent
+				got.R, got.G, got.B = 0, 0, 0
+				want.R, want.G, want.B = 0, 0, 0
+			}
+			if got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// noise returns an image of random colours, with random alpha if alpha is set
+func noise(rng *rand.Rand, width, height int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i] = uint8(rng.Intn(256))
+		img.Pix[i+1] = uint8(rng.Intn(256))
+		img.Pix[i+2] = uint8(rng.Intn(256))
+		img.Pix[i+3] = 0xff
+		if alpha {
+			img.Pix[i+3] = uint8(rng.Intn(256))
+		}
+	}
+	return img
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	solid := image.NewNRGBA(image.Rect(0, 0, 31, 17))
+	for i := 0; i < len(solid.Pix); i += 4 {
+		copy(solid.Pix[i:], []uint8{0x20, 0x80, 0xe0, 0xff})
+	}
+
+	// White shapes on black, like processed puzzle images, with anti-aliased grey edges
+	puzzle := image.NewGray(image.Rect(0, 0, 800, 600))
+	for y := 150; y < 450; y++ {
+		for x := 200; x < 600; x++ {
+			switch {
+			case (x-400)*(x-400)+(y-300)*(y-300) < 120*120:
+				puzzle.SetGray(x, y, color.Gray{Y: 0xff})
+			case (x-400)*(x-400)+(y-300)*(y-300) < 124*124:
+				puzzle.SetGray(x, y, color.Gray{Y: 0x80})
+			}
+		}
+	}
+
+	stripes := image.NewNRGBA(image.Rect(0, 0, 97, 61))
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 97; x++ {
+			v := uint8(0)
+			if (x/3+y/5)%2 == 0 {
+				v = 0xff
+			}
+			stripes.SetNRGBA(x, y, color.NRGBA{R: v, G: v / 2, B: 255 - v, A: 0xff})
+		}
+	}
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(255 - x), B: uint8(x * 7), A: uint8(x)})
+		}
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 5, 5))
+	transparent.SetNRGBA(2, 2, color.NRGBA{R: 0xff, A: 0x7f})
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"1x1", noise(rng, 1, 1, false)},
+		{"1x1 alpha", noise(rng, 1, 1, true)},
+		{"odd noise", noise(rng, 37, 23, false)},
+		{"odd noise alpha", noise(rng, 41, 19, true)},
+		{"single row", noise(rng, 1000, 1, false)},
+		{"single column", noise(rng, 1, 1000, true)},
+		{"solid", solid},
+		{"puzzle", puzzle},
+		{"stripes", stripes},
+		{"alpha gradient", gradient},
+		{"mostly transparent", transparent},
+		{"sub-image", noise(rng, 40, 40, true).SubImage(image.Rect(7, 9, 30, 22))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, tt.img)
+		})
+	}
+}
+
+// TestEncodeWebPLongCodes checks Huffman codes are limited to 15 bits
+// Fibonacci frequencies give an unlimited tree one level deeper per symbol.
+func TestEncodeWebPLongCodes(t *testing.T) {
+	var greens []uint8
+	a, b := 1, 1
+	for symbol := 0; symbol < 24; symbol++ {
+		for i := 0; i < a; i++ {
+			greens = append(greens, uint8(symbol*10))
+		}
+		a, b = b, a+b
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	rng.Shuffle(len(greens), func(i, j int) { greens[i], greens[j] = greens[j], greens[i] })
+
+	width := 500
+	height := (len(greens) + width - 1) / width
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, g := range greens {
+		img.SetNRGBA(i%width, i/width, color.NRGBA{G: g, A: 0xff})
+	}
+
+	roundTrip(t, img)
+}
+
+func TestEncodeWebPRejectsEmptyImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 10))); err == nil {
+		t.Error("EncodeWebP of an empty image succeeded")
+	}
+}
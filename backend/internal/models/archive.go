@@ -0,0 +1,27 @@
+package models
+
+// ArchiveDay summarizes the puzzles published on a single day
+type ArchiveDay struct {
+	Date         string         `json:"date"`
+	Count        int            `json:"count"`
+	Theme        string         `json:"theme"`
+	Difficulties map[string]int `json:"difficulties"` // Number of puzzles per difficulty level
+	Thumbnails   []string       `json:"thumbnails"`   // Image URLs in puzzle order
+}
+
+// ArchiveFilter narrows an archive listing
+// Empty fields are not applied
+type ArchiveFilter struct {
+	Through    string // Latest date to include (inclusive)
+	Before     string // Cursor: only dates strictly before this one
+	Month      string // YYYY-MM
+	Theme      string
+	Difficulty string // Days with at least one puzzle of this difficulty
+	Limit      int
+}
+
+// ArchiveResponse represents a page of archive days, newest first
+type ArchiveResponse struct {
+	Days       []ArchiveDay `json:"days"`
+	NextCursor string       `json:"nextCursor,omitempty"` // Pass as ?cursor= to fetch the next page
+}
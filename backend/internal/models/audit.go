@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Puzzle audit actions
+const (
+	AuditCreate       = "create"
+	AuditUpdate       = "update"
+	AuditReplaceImage = "replace_image"
+	AuditRollback     = "rollback_image"
+	AuditReorder      = "reorder"
+	AuditDelete       = "delete"
+	AuditApprove      = "approve"
+	AuditReject       = "reject"
+)
+
+// PuzzleAuditEntry records one change to a puzzle
+type PuzzleAuditEntry struct {
+	ID        int64           `json:"id"`
+	PuzzleID  string          `json:"puzzleId"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Before    json.RawMessage `json:"before,omitempty"` // Puzzle as JSON before the change (absent on create)
+	After     json.RawMessage `json:"after,omitempty"`  // Puzzle as JSON after the change (absent on delete)
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// PuzzleUpdate is a partial edit of a puzzle; nil fields are left unchanged
+type PuzzleUpdate struct {
+	Answer           *string   `json:"answer"`
+	AlternateAnswers *[]string `json:"alternateAnswers"`
+	Hint             *string   `json:"hint"`
+	Explanation      *string   `json:"explanation"`
+	Theme            *string   `json:"theme"`
+	Difficulty       *string   `json:"difficulty"`
+}
+
+// ReorderRequest sets the order of a day's puzzles
+// IDs lists every puzzle of the day in its new order; puzzle IDs themselves don't change.
+type ReorderRequest struct {
+	Date string   `json:"date"`
+	IDs  []string `json:"ids"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Per-date backfill outcomes
+const (
+	BackfillPending    = "pending"
+	BackfillGenerated  = "generated"
+	BackfillSkipped    = "skipped"     // Puzzles already existed and force wasn't set
+	BackfillInProgress = "in_progress" // Another instance was generating the date
+	BackfillOverBudget = "over_budget" // The run's generation budget was used up
+	BackfillCancelled  = "cancelled"
+	BackfillFailed     = "failed"
+)
+
+// BackfillRequest asks for puzzles to be generated for every date in [From, To]
+type BackfillRequest struct {
+	From        string `json:"from"`                  // YYYY-MM-DD, inclusive
+	To          string `json:"to"`                    // YYYY-MM-DD, inclusive
+	Force       bool   `json:"force"`                 // Regenerate dates that already have puzzles
+	Concurrency int    `json:"concurrency,omitempty"` // Dates generated at once (0 = configured limit)
+}
+
+// BackfillDateResult is the outcome for one date of a backfill
+type BackfillDateResult struct {
+	Date   string `json:"date"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BackfillReport describes a backfill run and its per-date results
+type BackfillReport struct {
+	ID          string               `json:"id"`
+	From        string               `json:"from"`
+	To          string               `json:"to"`
+	Force       bool                 `json:"force"`
+	Concurrency int                  `json:"concurrency"`
+	Budget      int                  `json:"budget"` // Maximum dates this run may generate (0 = unlimited)
+	StartedAt   time.Time            `json:"startedAt"`
+	FinishedAt  *time.Time           `json:"finishedAt,omitempty"`
+	Done        bool                 `json:"done"`
+	Summary     map[string]int       `json:"summary"` // Count of dates per status
+	Results     []BackfillDateResult `json:"results"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// BankPuzzle is a reserve puzzle kept for days when generation fails
+// Bank puzzles come in sets of five sharing a theme, and a whole set is promoted to a date at once.
+type BankPuzzle struct {
+	ID          int        `json:"id"`
+	SetID       string     `json:"setId"`
+	Index       int        `json:"index"`
+	Prompt      string     `json:"prompt"` // Prompt the image was generated from
+	Answer      string     `json:"answer"`
+	Hint        string     `json:"hint"`
+	Explanation string     `json:"explanation,omitempty"`
+	Theme       string     `json:"theme,omitempty"`
+	Difficulty  string     `json:"difficulty"`
+	ImageURL    string     `json:"imageUrl"`
+	ImagePath   string     `json:"-"`
+	Approved    bool       `json:"approved"`
+	UsedOn      string     `json:"usedOn,omitempty"` // Date the set was promoted to, empty while in reserve
+	CreatedAt   time.Time  `json:"createdAt"`
+	ApprovedAt  *time.Time `json:"approvedAt,omitempty"`
+
+	// Variants and placeholders, carried over when the set is promoted
+	ImageMeta
+}
+
+// BankSet is a set of bank puzzles promoted together
+type BankSet struct {
+	SetID    string       `json:"setId"`
+	Theme    string       `json:"theme,omitempty"`
+	Approved bool         `json:"approved"`
+	UsedOn   string       `json:"usedOn,omitempty"`
+	Puzzles  []BankPuzzle `json:"puzzles"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// ImageMeta is what's recorded about a stored image besides where it is
+type ImageMeta struct {
+	// Resized copies stored next to the image; empty for images saved before variants existed
+	ImageVariants []ImageVariant `json:"imageVariants,omitempty"`
+	BlurHash      string         `json:"blurHash,omitempty"`      // Placeholder to render while the image loads
+	DominantColor string         `json:"dominantColor,omitempty"` // Most common colour, as #rrggbb
+	ImageSHA256   string         `json:"imageSha256,omitempty"`   // Checksum of the stored PNG, which is also its name
+	ImageSize     int64          `json:"imageSize,omitempty"`     // Bytes in the stored PNG
+}
+
+// ImageVariant is a size a puzzle image is stored at, as PNG and WebP, next to the original
+type ImageVariant struct {
+	Name   string `json:"name"` // thumb, mobile or full
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Image variant names
+const (
+	VariantThumb  = "thumb"
+	VariantMobile = "mobile"
+	VariantFull   = "full"
+)
+
+// ImageVariantPath returns where a variant of an image is stored, given the original's path, key or URL
+// The full variant shares the original's name, so its PNG is the original itself:
+// "2025-01-01/0.png" has "2025-01-01/0-thumb.webp", "2025-01-01/0-mobile.png" and "2025-01-01/0.webp".
+func ImageVariantPath(original, name, format string) string {
+	base := strings.TrimSuffix(original, path.Ext(original))
+	if name == VariantFull {
+		return base + "." + format
+	}
+	return base + "-" + name + "." + format
+}
+
+// OriginalImagePath returns the path, key or URL of the image a variant belongs to, reversing ImageVariantPath
+func OriginalImagePath(variant string) string {
+	base := strings.TrimSuffix(variant, path.Ext(variant))
+	for _, name := range []string{VariantThumb, VariantMobile} {
+		if trimmed := strings.TrimSuffix(base, "-"+name); trimmed != base {
+			return trimmed + ".png"
+		}
+	}
+	return base + ".png"
+}
+
+// PublicImageVariant is an image variant as players see it
+type PublicImageVariant struct {
+	URL     string `json:"url"`     // PNG
+	WebPURL string `json:"webpUrl"` // Same image as lossless WebP
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+// PublicImageVariants maps variant names to their URLs, or returns nil if the image has none
+func PublicImageVariants(imageURL string, variants []ImageVariant) map[string]PublicImageVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	public := make(map[string]PublicImageVariant, len(variants))
+	for _, v := range variants {
+		public[v.Name] = PublicImageVariant{
+			URL:     ImageVariantPath(imageURL, v.Name, "png"),
+			WebPURL: ImageVariantPath(imageURL, v.Name, "webp"),
+			Width:   v.Width,
+			Height:  v.Height,
+		}
+	}
+	return public
+}
+
+// PuzzleImageVersion is an image a puzzle has used, kept so an editor can roll back to it
+type PuzzleImageVersion struct {
+	ID        int64  `json:"id"`
+	PuzzleID  string `json:"puzzleId"`
+	ImageURL  string `json:"imageUrl"`
+	ImagePath string `json:"imagePath"`
+	ImageMeta
+	CreatedAt time.Time `json:"createdAt"` // When the puzzle first used the image
+	Current   bool      `json:"current"`   // Whether the puzzle uses the image now
+}
+
+// ImageRollbackRequest picks the image version a puzzle rolls back to
+type ImageRollbackRequest struct {
+	VersionID int64 `json:"versionId"`
+}
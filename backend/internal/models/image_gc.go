@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// What the image garbage collector does with orphaned images
+const (
+	ImageGCNone       = "none" // Dry run: only report them
+	ImageGCDelete     = "delete"
+	ImageGCQuarantine = "quarantine" // Move them aside, where they're kept but no longer listed
+)
+
+// StoredImage is a file in the image store
+type StoredImage struct {
+	Path         string    `json:"path"` // S3 key or file path, as recorded in image_path
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// ImageGCReport is the result of an image garbage collection pass
+type ImageGCReport struct {
+	Action      string        `json:"action"`  // none, delete or quarantine
+	Grace       string        `json:"grace"`   // Orphans modified more recently than this are left alone
+	Scanned     int           `json:"scanned"` // Images in the store
+	Referenced  int           `json:"referenced"`
+	Recent      int           `json:"recent"`  // Orphans still within the grace period
+	Orphans     []StoredImage `json:"orphans"` // Orphans past the grace period
+	OrphanBytes int64         `json:"orphanBytes"`
+	Failed      []string      `json:"failed,omitempty"` // Orphans that couldn't be removed
+}
@@ -0,0 +1,22 @@
+package models
+
+// Per-image outcomes of a storage migration
+const (
+	ImageMigrated           = "migrated"
+	ImageAlreadyMigrated    = "already_migrated" // Its rows already point at the destination
+	ImageWouldMigrate       = "would_migrate"    // Dry run
+	ImageMissing            = "missing"          // Neither backend has the image
+	ImageMigrationFailed    = "failed"
+	ImageMigrationCancelled = "cancelled"
+)
+
+// ImageMigrationResult is the outcome of moving one image, with its variants, to another storage backend
+type ImageMigrationResult struct {
+	OldPath string `json:"oldPath"`
+	NewPath string `json:"newPath,omitempty"`
+	Status  string `json:"status"`
+	Files   int    `json:"files"` // The image and its variants
+	Bytes   int64  `json:"bytes"`
+	Rows    int64  `json:"rows"` // Puzzle and bank rows repointed at the copy
+	Error   string `json:"error,omitempty"`
+}
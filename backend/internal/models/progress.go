@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Player progress statuses for a single puzzle
+const (
+	ProgressInProgress = "in_progress"
+	ProgressSolved     = "solved"
+	ProgressFailed     = "failed"
+)
+
+// PlayerProgress tracks a player's attempts on a single puzzle
+type PlayerProgress struct {
+	PlayerID  string    `json:"playerId"`
+	PuzzleID  string    `json:"puzzleId"`
+	Attempts  int       `json:"attempts"`
+	Status    string    `json:"status"` // in_progress, solved or failed
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsFinished reports whether the player can no longer guess this puzzle
+func (p *PlayerProgress) IsFinished() bool {
+	return p.Status == ProgressSolved || p.Status == ProgressFailed
+}
@@ -1,29 +1,162 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 // Puzzle represents a rebus puzzle with image, answer, and hint
 type Puzzle struct {
 	ID        string `json:"id"`       // Unique identifier: "YYYY-MM-DD-index"
 	ImageURL  string `json:"imageUrl"` // URL to puzzle image (relative or absolute)
 	ImagePath string `json:"-"`        // Local file path to the stored image
 	Answer    string `json:"answer"`   // Correct answer (lowercase)
-	Hint      string `json:"hint"`     // Hint for the puzzle
-	Date      string `json:"date"`     // Date in YYYY-MM-DD format
-	Index     int    `json:"index"`    // Puzzle number (0-4)
+	// Variants and loading placeholders for the image
+	ImageMeta
+	// Other accepted answers (lowercase)
+	AlternateAnswers []string `json:"alternateAnswers,omitempty"`
+	Hint             string   `json:"hint"`        // Hint for the puzzle
+	Explanation      string   `json:"explanation"` // How the wordplay works (revealed with the solution)
+	Theme            string   `json:"theme"`       // Theme shared by the day's puzzles
+	Difficulty       string   `json:"difficulty"`  // easy, medium or hard
+	Date             string   `json:"date"`        // Date in YYYY-MM-DD format
+	Index            int      `json:"index"`       // Puzzle number (0-4)
+	// Review state (see PuzzleStatus constants)
+	Status      string     `json:"status"`
+	ReviewNotes string     `json:"reviewNotes,omitempty"`
+	ReviewedBy  string     `json:"reviewedBy,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	UpdatedAt   time.Time  `json:"updatedAt"` // Last time the row changed
+}
+
+// Puzzle review statuses
+// Generated puzzles start in review when review is required and approved otherwise;
+// approved puzzles become published once their date is released.
+const (
+	PuzzleDraft     = "draft"
+	PuzzleInReview  = "in_review"
+	PuzzleApproved  = "approved"
+	PuzzleRejected  = "rejected"
+	PuzzlePublished = "published"
+)
+
+// IsPlayable reports whether a puzzle has passed review and may be shown to players
+func (p *Puzzle) IsPlayable() bool {
+	return p.Status == PuzzleApproved || p.Status == PuzzlePublished
+}
+
+// Accepts reports whether a guess matches the answer or one of its alternates (case-insensitive, trimmed)
+func (p *Puzzle) Accepts(guess string) bool {
+	guess = NormalizeAnswer(guess)
+	if guess == NormalizeAnswer(p.Answer) {
+		return true
+	}
+	for _, alternate := range p.AlternateAnswers {
+		if guess == NormalizeAnswer(alternate) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAnswer lowercases and trims an answer for storage and comparison
+func NormalizeAnswer(answer string) string {
+	return strings.ToLower(strings.TrimSpace(answer))
+}
+
+// IsPending reports whether a puzzle is waiting for a reviewer
+func (p *Puzzle) IsPending() bool {
+	return p.Status == PuzzleDraft || p.Status == PuzzleInReview
+}
+
+// ReviewRequest approves or rejects a puzzle
+type ReviewRequest struct {
+	Notes string `json:"notes"`
+}
+
+// Puzzle difficulty levels
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// IsValidDifficulty reports whether d is a known difficulty level
+func IsValidDifficulty(d string) bool {
+	return d == DifficultyEasy || d == DifficultyMedium || d == DifficultyHard
+}
+
+// PublicPuzzle is the player-facing view of a puzzle, without the answer
+type PublicPuzzle struct {
+	ID         string `json:"id"`
+	ImageURL   string `json:"imageUrl"`
+	Hint       string `json:"hint"`
+	Theme      string `json:"theme"`
+	Difficulty string `json:"difficulty"`
+	Date       string `json:"date"`
+	Index      int    `json:"index"`
+
+	// Variant name to URLs and size, for building a srcset; omitted for images without variants
+	Variants map[string]PublicImageVariant `json:"variants,omitempty"`
+	// Placeholders to render while the image loads; omitted for images saved before they were computed
+	BlurHash      string `json:"blurHash,omitempty"`
+	DominantColor string `json:"dominantColor,omitempty"`
+}
+
+// NewPublicPuzzle converts a puzzle to its player-facing view
+func NewPublicPuzzle(p Puzzle) PublicPuzzle {
+	return PublicPuzzle{
+		ID:         p.ID,
+		ImageURL:   p.ImageURL,
+		Hint:       p.Hint,
+		Theme:      p.Theme,
+		Difficulty: p.Difficulty,
+		Date:       p.Date,
+		Index:      p.Index,
+		Variants:   PublicImageVariants(p.ImageURL, p.ImageVariants),
+
+		BlurHash:      p.BlurHash,
+		DominantColor: p.DominantColor,
+	}
 }
 
 // VerifyRequest represents a request to verify an answer
 type VerifyRequest struct {
 	PuzzleID string `json:"puzzleId"`
+	PlayerID string `json:"playerId"` // Optional, falls back to the X-Player-ID header
 	Answer   string `json:"answer"`
 }
 
 // VerifyResponse represents the response to a verification request
 type VerifyResponse struct {
-	Correct bool `json:"correct"`
+	Correct           bool   `json:"correct"`
+	Status            string `json:"status"`                      // Player's progress status for the puzzle
+	RemainingAttempts *int   `json:"remainingAttempts,omitempty"` // Omitted when attempts are unlimited
+	Answer            string `json:"answer,omitempty"`            // Revealed once the puzzle is solved or failed
+}
+
+// GiveUpRequest represents a request to give up on a puzzle
+type GiveUpRequest struct {
+	PlayerID string `json:"playerId"` // Optional, falls back to the X-Player-ID header
+}
+
+// GiveUpResponse represents the response to a give up request
+type GiveUpResponse struct {
+	PuzzleID string `json:"puzzleId"`
+	Answer   string `json:"answer"`
+	Status   string `json:"status"`
+}
+
+// SolutionResponse represents the revealed solution of a puzzle
+type SolutionResponse struct {
+	PuzzleID    string `json:"puzzleId"`
+	Answer      string `json:"answer"`
+	Hint        string `json:"hint"`
+	Explanation string `json:"explanation"`
 }
 
 // PuzzlesResponse represents the response containing puzzles for a date
 type PuzzlesResponse struct {
-	Date    string   `json:"date"`
-	Puzzles []Puzzle `json:"puzzles"`
+	Date    string         `json:"date"`
+	Puzzles []PublicPuzzle `json:"puzzles"`
 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the stored state of one token bucket
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
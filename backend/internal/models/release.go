@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ReleaseRequest sets the release time for a date
+type ReleaseRequest struct {
+	ReleaseAt time.Time `json:"releaseAt"` // RFC 3339 timestamp
+}
+
+// ReleaseResponse describes when a date's puzzles are released
+type ReleaseResponse struct {
+	Date      string    `json:"date"`
+	ReleaseAt time.Time `json:"releaseAt"`
+	Released  bool      `json:"released"`
+	Override  bool      `json:"override"` // True when the date has its own release time
+}
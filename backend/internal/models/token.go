@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// APIToken is a stored credential for the admin API
+// Only the SHA-256 hash of the token is stored; the token itself is shown once when it's created.
+type APIToken struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	TokenHash  string     `json:"-"`
+	CreatedBy  string     `json:"createdBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateTokenRequest asks for a new API token
+type CreateTokenRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"` // viewer, editor or admin
+}
+
+// CreateTokenResponse returns a new API token along with its secret
+type CreateTokenResponse struct {
+	APIToken
+	Token string `json:"token"` // Only returned once
+}
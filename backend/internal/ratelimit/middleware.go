@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// KeyFunc identifies who a request should be counted against
+type KeyFunc func(r *http.Request) string
+
+// ByIP counts requests per client IP
+// trustForwarded uses the first X-Forwarded-For address, which is only safe behind a proxy that sets it.
+func ByIP(trustForwarded bool) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + clientIP(r, trustForwarded)
+	}
+}
+
+// ByPlayer counts requests per X-Player-ID, falling back to the client IP for requests without one
+// Clients choose their own player IDs, so pair it with a ByIP limit: a fresh ID gets a fresh bucket.
+func ByPlayer(trustForwarded bool) KeyFunc {
+	byIP := ByIP(trustForwarded)
+	return func(r *http.Request) string {
+		if playerID := strings.TrimSpace(r.Header.Get("X-Player-ID")); playerID != "" {
+			return "player:" + playerID
+		}
+		return byIP(r)
+	}
+}
+
+// Middleware limits requests to rule per key, answering 429 Too Many Requests with Retry-After
+// route namespaces the buckets so each route is limited separately. If the limiter fails
+// the request is let through, so a database problem doesn't take the endpoint down.
+// Middlewares can be stacked to apply several limits; the headers report the tightest.
+func Middleware(limiter Limiter, route string, rule Rule, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !rule.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.Allow(route+":"+key(r), rule)
+			if err != nil {
+				log.Printf("Rate limiter failed for %s, allowing request: %v", route, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if remaining, err := strconv.Atoi(w.Header().Get("X-RateLimit-Remaining")); err != nil || result.Remaining < remaining {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			}
+			if !result.Allowed {
+				retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, "Too many requests, try again in "+strconv.Itoa(retryAfter)+"s", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the request's client address without its port
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
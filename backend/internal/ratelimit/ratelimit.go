@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Rule allows Limit requests per Period, refilled evenly, with bursts of up to Limit
+// The zero Rule disables limiting.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRule parses a rule such as "30/1m", "5/h" or "100/30s"
+// An empty string or "off" returns the zero Rule.
+func ParseRule(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Rule{}, nil
+	}
+
+	limitPart, periodPart, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q must look like 30/1m", s)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(limitPart))
+	if err != nil || limit <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q must start with a positive count", s)
+	}
+
+	// A bare unit means one of it, so "5/h" is "5/1h"
+	periodPart = strings.TrimSpace(periodPart)
+	if periodPart != "" && (periodPart[0] < '0' || periodPart[0] > '9') {
+		periodPart = "1" + periodPart
+	}
+	period, err := time.ParseDuration(periodPart)
+	if err != nil || period <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q must end with a positive duration", s)
+	}
+
+	return Rule{Limit: limit, Period: period}, nil
+}
+
+// Enabled reports whether the rule limits anything
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Period > 0
+}
+
+// String formats the rule the way ParseRule reads it
+func (r Rule) String() string {
+	if !r.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", r.Limit, r.Period)
+}
+
+// ratePerSecond is how many tokens the bucket regains each second
+func (r Rule) ratePerSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // Whole tokens left after this request
+	RetryAfter time.Duration // How long until a token is available; zero when allowed
+}
+
+// Limiter takes tokens from per-key buckets
+type Limiter interface {
+	Allow(key string, rule Rule) (Result, error)
+}
+
+// take refills a bucket for the time since it was last updated and spends a token if one is available
+func take(tokens float64, updatedAt, now time.Time, rule Rule) (float64, Result) {
+	rate := rule.ratePerSecond()
+	if elapsed := now.Sub(updatedAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(rule.Limit), tokens+elapsed*rate)
+	}
+
+	result := Result{Limit: rule.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(tokens)
+
+	return tokens, result
+}
+
+// memorySweepInterval is how often MemoryLimiter drops buckets that have refilled
+const memorySweepInterval = time.Minute
+
+// memoryBucket is a token bucket held by MemoryLimiter
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time // When the bucket will have refilled and can be forgotten
+}
+
+// MemoryLimiter keeps token buckets in process memory
+// Each replica counts separately, so use PostgresLimiter when running more than one.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter creates an empty in-memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from key's bucket
+func (l *MemoryLimiter) Allow(key string, rule Rule) (Result, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= memorySweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(rule.Limit), updatedAt: now}
+		l.buckets[key] = b
+	}
+
+	var result Result
+	b.tokens, result = take(b.tokens, b.updatedAt, now, rule)
+	b.updatedAt = now
+	b.fullAt = now.Add(time.Duration((float64(rule.Limit) - b.tokens) / rule.ratePerSecond() * float64(time.Second)))
+
+	return result, nil
+}
+
+// sweep forgets buckets that have refilled, since a new bucket starts full anyway
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if !now.Before(b.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// BucketStore persists token buckets
+type BucketStore interface {
+	UpdateRateLimitBucket(key string, capacity float64, update func(b *models.RateLimitBucket)) error
+}
+
+// PostgresLimiter keeps token buckets in the rate_limits table so every replica shares them
+type PostgresLimiter struct {
+	store BucketStore
+}
+
+// NewPostgresLimiter creates a limiter backed by the database
+func NewPostgresLimiter(store BucketStore) *PostgresLimiter {
+	return &PostgresLimiter{store: store}
+}
+
+// Allow takes a token from key's bucket, locking its row for the update
+func (l *PostgresLimiter) Allow(key string, rule Rule) (Result, error) {
+	var result Result
+	err := l.store.UpdateRateLimitBucket(key, float64(rule.Limit), func(b *models.RateLimitBucket) {
+		now := time.Now()
+		b.Tokens, result = take(b.Tokens, b.UpdatedAt, now, rule)
+		b.UpdatedAt = now
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package release
+
+import (
+	"fmt"
+	"time"
+
+	"backend/internal/store"
+)
+
+// Policy decides when a date's puzzles become visible to players
+// Puzzles may be generated ahead of time but are hidden until their release time:
+// the date's override from the puzzle_releases table, or the default hour on that date.
+// The default hour is interpreted in the player's timezone when one is given, so a player
+// in Tokyo gets their local day's puzzles; otherwise the publication timezone is used.
+type Policy struct {
+	store       *store.Store
+	location    *time.Location
+	defaultHour int
+}
+
+// NewPolicy creates a release policy for the publication timezone and default release hour
+func NewPolicy(store *store.Store, location *time.Location, defaultHour int) (*Policy, error) {
+	if defaultHour < 0 || defaultHour > 23 {
+		return nil, fmt.Errorf("invalid release hour %d: must be between 0 and 23", defaultHour)
+	}
+
+	return &Policy{
+		store:       store,
+		location:    location,
+		defaultHour: defaultHour,
+	}, nil
+}
+
+// Location returns the publication timezone
+func (p *Policy) Location() *time.Location {
+	return p.location
+}
+
+// Today returns the current date (YYYY-MM-DD) for a player
+// A nil playerLoc means the publication timezone.
+func (p *Policy) Today(now time.Time, playerLoc *time.Location) string {
+	return now.In(p.resolve(playerLoc)).Format("2006-01-02")
+}
+
+// ReleaseTime returns when a date's puzzles are released for a player
+// Per-date overrides are absolute instants and apply to every timezone.
+func (p *Policy) ReleaseTime(date string, playerLoc *time.Location) (time.Time, error) {
+	releaseAt, found, err := p.store.GetReleaseTime(date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if found {
+		return releaseAt, nil
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, p.resolve(playerLoc))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %w", err)
+	}
+
+	return day.Add(time.Duration(p.defaultHour) * time.Hour), nil
+}
+
+// IsReleased reports whether a date's puzzles are visible to a player at now
+func (p *Policy) IsReleased(date string, now time.Time, playerLoc *time.Location) (bool, error) {
+	releaseAt, err := p.ReleaseTime(date, playerLoc)
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(releaseAt), nil
+}
+
+// LatestReleasedDate returns the most recent date whose puzzles are visible to a player at now
+// This is the player's today, or yesterday if today hasn't been released yet.
+func (p *Policy) LatestReleasedDate(now time.Time, playerLoc *time.Location) (string, error) {
+	today := p.Today(now, playerLoc)
+
+	released, err := p.IsReleased(today, now, playerLoc)
+	if err != nil {
+		return "", err
+	}
+	if released {
+		return today, nil
+	}
+
+	return now.In(p.resolve(playerLoc)).AddDate(0, 0, -1).Format("2006-01-02"), nil
+}
+
+// resolve returns the player's timezone, falling back to the publication timezone
+func (p *Policy) resolve(playerLoc *time.Location) *time.Location {
+	if playerLoc == nil {
+		return p.location
+	}
+	return playerLoc
+}
@@ -0,0 +1,385 @@
+// Package s3fake is an in-memory S3-compatible server for tests
+// It speaks enough of the path-style S3 REST API for SupabaseStorage: PutObject (including
+// copies), GetObject, HeadObject, DeleteObject and ListObjects (V1 and V2). Buckets exist as
+// soon as they're used. Signatures aren't checked, but requests without credentials can only
+// read public-read objects, and expired presigned URLs are refused, like a private bucket.
+package s3fake
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Object is an object stored in the fake
+type Object struct {
+	Data         []byte
+	ContentType  string
+	ACL          string // Canned ACL it was uploaded with, e.g. public-read; empty for private
+	ETag         string // Quoted MD5 of Data, as S3 reports it
+	LastModified time.Time
+}
+
+// Server is an S3-compatible HTTP server keeping objects in memory
+type Server struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*Object // Objects by bucket and key
+}
+
+// New starts a server; Close it when done
+func New() *Server {
+	s := &Server{buckets: make(map[string]map[string]*Object)}
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// URL is the endpoint to point an S3 client at, with path-style addressing
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// PutObject stores an object directly, bypassing HTTP
+func (s *Server) PutObject(bucket, key string, data []byte, contentType, acl string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(bucket, key, &Object{
+		Data:         append([]byte(nil), data...),
+		ContentType:  contentType,
+		ACL:          acl,
+		ETag:         etag(data),
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	})
+}
+
+// GetObject returns a copy of a stored object, or false if the key doesn't exist
+func (s *Server) GetObject(bucket, key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	object, ok := s.buckets[bucket][key]
+	if !ok {
+		return Object{}, false
+	}
+	copied := *object
+	copied.Data = append([]byte(nil), object.Data...)
+	return copied, true
+}
+
+// Keys returns the keys in a bucket, sorted
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ServeHTTP handles an S3 request addressed as /{bucket}/{key}
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		writeError(w, r, http.StatusBadRequest, "InvalidBucketName", "The bucket name is missing")
+		return
+	}
+
+	if code, message := checkPresigned(r.URL.Query(), time.Now()); code != "" {
+		writeError(w, r, http.StatusForbidden, code, message)
+		return
+	}
+	signed := r.Header.Get("Authorization") != "" || r.URL.Query().Get("X-Amz-Signature") != ""
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		if !signed {
+			writeError(w, r, http.StatusForbidden, "AccessDenied", "Listing requires credentials")
+			return
+		}
+		s.listObjects(w, r, bucket)
+	case key == "":
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "Bucket operations aren't supported")
+	case r.Method == http.MethodPut && !signed, r.Method == http.MethodDelete && !signed:
+		writeError(w, r, http.StatusForbidden, "AccessDenied", "Writes require credentials")
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copyObject(w, r, bucket, key)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, bucket, key)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.getObject(w, r, bucket, key, signed)
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.buckets[bucket], key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed", "The method isn't allowed")
+	}
+}
+
+// putObject stores the request body under key
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	object := &Object{
+		Data:         data,
+		ContentType:  r.Header.Get("Content-Type"),
+		ACL:          r.Header.Get("X-Amz-Acl"),
+		ETag:         etag(data),
+		LastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	s.mu.Lock()
+	s.put(bucket, key, object)
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", object.ETag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyObject copies the object named by the X-Amz-Copy-Source header to key
+// Like S3, the copy gets the ACL sent with the request rather than the source's.
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", "Invalid copy source")
+		return
+	}
+	sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+
+	s.mu.Lock()
+	original, ok := s.buckets[sourceBucket][sourceKey]
+	var object *Object
+	if ok {
+		object = &Object{
+			Data:         original.Data,
+			ContentType:  original.ContentType,
+			ACL:          r.Header.Get("X-Amz-Acl"),
+			ETag:         original.ETag,
+			LastModified: time.Now().UTC().Truncate(time.Second),
+		}
+		s.put(bucket, key, object)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	writeXML(w, copyObjectResult{ETag: object.ETag, LastModified: object.LastModified.Format(time.RFC3339)})
+}
+
+// getObject serves an object, or only its headers for HEAD
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, bucket, key string, signed bool) {
+	s.mu.Lock()
+	object, ok := s.buckets[bucket][key]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	if !signed && object.ACL != "public-read" && object.ACL != "public-read-write" {
+		writeError(w, r, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(object.Data)))
+	w.Header().Set("ETag", object.ETag)
+	w.Header().Set("Last-Modified", object.LastModified.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(object.Data)
+	}
+}
+
+// listObjects lists a bucket's keys in order, in pages of max-keys (1000 by default)
+// V2 requests (list-type=2) page with continuation-token or start-after, V1 requests with marker.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+
+	maxKeys := 1000
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "Invalid max-keys")
+			return
+		}
+		maxKeys = n
+	}
+
+	after := query.Get("marker")
+	if v2 {
+		after = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			after = token
+		}
+	}
+
+	result := listBucketResult{Name: bucket, Prefix: prefix, MaxKeys: maxKeys}
+	if v2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		result.StartAfter = query.Get("start-after")
+	} else {
+		result.Marker = query.Get("marker")
+	}
+
+	s.mu.Lock()
+	objects := s.buckets[bucket]
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		object := objects[key]
+		result.Contents = append(result.Contents, listEntry{
+			Key:          key,
+			LastModified: object.LastModified.Format(time.RFC3339),
+			ETag:         object.ETag,
+			Size:         int64(len(object.Data)),
+			StorageClass: "STANDARD",
+		})
+	}
+	s.mu.Unlock()
+
+	if result.IsTruncated && len(result.Contents) > 0 {
+		last := result.Contents[len(result.Contents)-1].Key
+		if v2 {
+			result.NextContinuationToken = last
+		} else {
+			result.NextMarker = last
+		}
+	}
+	if v2 {
+		result.KeyCount = len(result.Contents)
+	}
+
+	writeXML(w, result)
+}
+
+// put stores an object; the caller holds mu
+func (s *Server) put(bucket, key string, object *Object) {
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]*Object)
+	}
+	s.buckets[bucket][key] = object
+}
+
+// checkPresigned returns an error code if a presigned request has expired or is malformed
+// Requests that aren't presigned pass.
+func checkPresigned(query url.Values, now time.Time) (code, message string) {
+	if query.Get("X-Amz-Signature") == "" {
+		return "", ""
+	}
+
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return "AuthorizationQueryParametersError", "X-Amz-Date is missing or invalid"
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 {
+		return "AuthorizationQueryParametersError", "X-Amz-Expires is missing or invalid"
+	}
+	if now.After(signedAt.Add(time.Duration(expires) * time.Second)) {
+		return "AccessDenied", "Request has expired"
+	}
+	return "", ""
+}
+
+// etag returns the quoted MD5 of data
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// errorResponse is the body of an S3 error
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+// copyObjectResult is the body of a CopyObject response
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+// listBucketResult is the body of a ListObjects or ListObjectsV2 response
+type listBucketResult struct {
+	XMLName               xml.Name    `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string      `xml:"Name"`
+	Prefix                string      `xml:"Prefix"`
+	Marker                string      `xml:"Marker,omitempty"`
+	NextMarker            string      `xml:"NextMarker,omitempty"`
+	StartAfter            string      `xml:"StartAfter,omitempty"`
+	ContinuationToken     string      `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string      `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int         `xml:"KeyCount,omitempty"`
+	MaxKeys               int         `xml:"MaxKeys"`
+	IsTruncated           bool        `xml:"IsTruncated"`
+	Contents              []listEntry `xml:"Contents"`
+}
+
+// listEntry is one object in a listing
+type listEntry struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// writeError writes an S3 error response; HEAD responses have no body, as in S3
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	body, _ := xml.Marshal(errorResponse{Code: code, Message: message, Resource: r.URL.Path})
+	fmt.Fprintf(w, "%s%s", xml.Header, body)
+}
+
+// writeXML writes a successful XML response
+func writeXML(w http.ResponseWriter, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(w, "%s%s", xml.Header, body)
+}
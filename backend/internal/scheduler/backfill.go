@@ -0,0 +1,255 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// maxBackfillDays caps the size of a single backfill range
+const maxBackfillDays = 366
+
+// Backfill is a running or finished backfill of a date range
+type Backfill struct {
+	mu     sync.Mutex
+	report models.BackfillReport
+
+	// Dates generated so far, counted against the report's budget
+	spent int
+}
+
+// Report returns a snapshot of the backfill's progress
+func (b *Backfill) Report() models.BackfillReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	report := b.report
+	report.Results = append([]models.BackfillDateResult(nil), b.report.Results...)
+	report.Summary = make(map[string]int)
+	for _, result := range report.Results {
+		report.Summary[result.Status]++
+	}
+	return report
+}
+
+// Backfill generates puzzles for every date in the request's range and waits for it to finish
+// Cancelling ctx stops new dates from starting; dates already generating are allowed to finish.
+func (s *Scheduler) Backfill(ctx context.Context, req models.BackfillRequest) (models.BackfillReport, error) {
+	backfill, err := s.newBackfill(req)
+	if err != nil {
+		return models.BackfillReport{}, err
+	}
+
+	s.runBackfill(ctx, backfill)
+	return backfill.Report(), nil
+}
+
+// StartBackfill starts a backfill in the background and returns it so progress can be polled
+// The backfill is stopped along with the scheduler.
+func (s *Scheduler) StartBackfill(req models.BackfillRequest) (*Backfill, error) {
+	backfill, err := s.newBackfill(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.backfillsMu.Lock()
+	s.backfills[backfill.report.ID] = backfill
+	s.backfillsMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		go func() {
+			select {
+			case <-s.stopChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		s.runBackfill(ctx, backfill)
+	}()
+
+	return backfill, nil
+}
+
+// GetBackfill returns a backfill started on this instance, or nil
+func (s *Scheduler) GetBackfill(id string) *Backfill {
+	s.backfillsMu.Lock()
+	defer s.backfillsMu.Unlock()
+	return s.backfills[id]
+}
+
+// newBackfill validates a backfill request and builds its pending report
+func (s *Scheduler) newBackfill(req models.BackfillRequest) (*Backfill, error) {
+	if err := store.ValidateDate(req.From); err != nil {
+		return nil, fmt.Errorf("invalid from date: %w", err)
+	}
+	if err := store.ValidateDate(req.To); err != nil {
+		return nil, fmt.Errorf("invalid to date: %w", err)
+	}
+	// Dates are YYYY-MM-DD so string comparison orders them chronologically
+	if req.From > req.To {
+		return nil, fmt.Errorf("from date %s is after to date %s", req.From, req.To)
+	}
+
+	var results []models.BackfillDateResult
+	for date := req.From; date <= req.To; date = addDays(date, 1) {
+		if len(results) == maxBackfillDays {
+			return nil, fmt.Errorf("date range is longer than %d days", maxBackfillDays)
+		}
+		results = append(results, models.BackfillDateResult{Date: date, Status: models.BackfillPending})
+	}
+
+	concurrency := s.config.BackfillConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if req.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must be positive")
+	}
+	if req.Concurrency > 0 && req.Concurrency < concurrency {
+		concurrency = req.Concurrency
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backfill{
+		report: models.BackfillReport{
+			ID:          id,
+			From:        req.From,
+			To:          req.To,
+			Force:       req.Force,
+			Concurrency: concurrency,
+			Budget:      s.config.BackfillBudget,
+			StartedAt:   time.Now(),
+			Results:     results,
+		},
+	}, nil
+}
+
+// runBackfill works through a backfill's dates with its configured concurrency
+func (s *Scheduler) runBackfill(ctx context.Context, b *Backfill) {
+	b.mu.Lock()
+	report := b.report
+	b.mu.Unlock()
+
+	log.Printf("Backfill %s: generating %s through %s (force=%t, concurrency=%d)", report.ID, report.From, report.To, report.Force, report.Concurrency)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < report.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				s.backfillDate(ctx, b, index)
+			}
+		}()
+	}
+
+	for i := range report.Results {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	finished := time.Now()
+	b.mu.Lock()
+	b.report.FinishedAt = &finished
+	b.report.Done = true
+	b.mu.Unlock()
+
+	summary := b.Report().Summary
+	log.Printf("Backfill %s finished: %v", report.ID, summary)
+}
+
+// backfillDate generates one date of a backfill and records the outcome
+func (s *Scheduler) backfillDate(ctx context.Context, b *Backfill, index int) {
+	date := b.report.Results[index].Date
+
+	if ctx.Err() != nil {
+		b.setResult(index, models.BackfillCancelled, nil)
+		return
+	}
+
+	if !b.report.Force && s.store.HasPuzzlesForDate(date) {
+		b.setResult(index, models.BackfillSkipped, nil)
+		return
+	}
+
+	if !b.reserve() {
+		b.setResult(index, models.BackfillOverBudget, nil)
+		return
+	}
+
+	generated, err := s.TriggerManualGeneration(date, b.report.Force)
+	switch {
+	case errors.Is(err, ErrGenerationInProgress):
+		b.refund()
+		b.setResult(index, models.BackfillInProgress, err)
+	case err != nil:
+		log.Printf("Backfill: failed to generate puzzles for %s: %v", date, err)
+		b.setResult(index, models.BackfillFailed, err)
+	case !generated:
+		// Another instance finished the date between the check above and taking its lock
+		b.refund()
+		b.setResult(index, models.BackfillSkipped, nil)
+	default:
+		b.setResult(index, models.BackfillGenerated, nil)
+	}
+}
+
+// reserve takes one date from the backfill's budget, returning false once it's used up
+// Failed generations still count, since the provider calls were made.
+func (b *Backfill) reserve() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.report.Budget > 0 && b.spent >= b.report.Budget {
+		return false
+	}
+	b.spent++
+	return true
+}
+
+// refund returns a reserved date to the budget when nothing was generated
+func (b *Backfill) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent--
+}
+
+// setResult records the outcome for one date
+func (b *Backfill) setResult(index int, status string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.Results[index].Status = status
+	if err != nil {
+		b.report.Results[index].Error = err.Error()
+	}
+}
+
+// newID returns a random identifier for a backfill or bank set
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
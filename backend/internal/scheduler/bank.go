@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// promoteBankSet publishes the oldest approved reserve set as a date's puzzles
+// Returns false if the date already has playable puzzles or the bank has no approved sets.
+// Puzzles still in review (or rejected) are replaced, since SavePuzzles deletes the date's rows other than drafts.
+// Promoted puzzles keep pointing at the bank's images, so a late generation run can't overwrite them.
+func (s *Scheduler) promoteBankSet(date string) (bool, error) {
+	existing, err := s.store.GetPuzzlesForDate(date)
+	if err != nil {
+		return false, err
+	}
+	var replaced []string
+	for _, p := range existing {
+		if p.IsPlayable() {
+			return false, nil
+		}
+		if p.Status == models.PuzzleDraft {
+			continue
+		}
+		replaced = append(replaced, fmt.Sprintf("%s (%s)", p.ID, p.Status))
+	}
+
+	bankPuzzles, err := s.store.ClaimBankSet(date)
+	if err != nil {
+		return false, err
+	}
+	if len(bankPuzzles) == 0 {
+		log.Printf("Puzzle bank is empty, no fallback available for %s", date)
+		return false, nil
+	}
+
+	setID := bankPuzzles[0].SetID
+	puzzles := make([]models.Puzzle, len(bankPuzzles))
+	for i, p := range bankPuzzles {
+		puzzles[i] = models.Puzzle{
+			ID:          fmt.Sprintf("%s-%d", date, p.Index),
+			ImageURL:    p.ImageURL,
+			ImagePath:   p.ImagePath,
+			ImageMeta:   p.ImageMeta,
+			Answer:      p.Answer,
+			Hint:        p.Hint,
+			Explanation: p.Explanation,
+			Theme:       p.Theme,
+			Difficulty:  p.Difficulty,
+			Date:        date,
+			Index:       p.Index,
+			Status:      models.PuzzleApproved, // Bank sets were reviewed before they could be claimed
+		}
+	}
+
+	if len(replaced) > 0 {
+		log.Printf("Replacing %d unplayable puzzles for %s with bank set %s: %v", len(replaced), date, setID, replaced)
+	}
+	if err := s.store.SavePuzzles(date, puzzles); err != nil {
+		if unclaimErr := s.store.UnclaimBankSet(setID); unclaimErr != nil {
+			log.Printf("Failed to return bank set %s to the reserve: %v", setID, unclaimErr)
+		}
+		return false, fmt.Errorf("failed to save promoted puzzles: %w", err)
+	}
+
+	log.Printf("Promoted reserve bank set %s to %s", setID, date)
+	return true, nil
+}
+
+// runFallbackJob promotes a bank set if today still has no playable puzzles by the job's scheduled deadline
+// Puzzles still waiting for review don't count, since players can't see them.
+func (s *Scheduler) runFallbackJob() error {
+	today := store.GetTodayDate()
+	if s.store.HasPlayablePuzzlesForDate(today) {
+		return nil
+	}
+
+	log.Printf("Fallback: no playable puzzles for %s by the deadline", today)
+	promoted, err := s.promoteBankSet(today)
+	if err != nil {
+		return err
+	}
+	if !promoted && !s.store.HasPlayablePuzzlesForDate(today) {
+		return fmt.Errorf("no playable puzzles for %s and the bank has no approved sets", today)
+	}
+	return nil
+}
+
+// runBankTopupJob generates reserve sets until the bank holds BankTargetSets unused sets
+// Sets awaiting approval count towards the target so unreviewed sets don't pile up.
+func (s *Scheduler) runBankTopupJob() error {
+	reserve, err := s.store.CountReserveBankSets()
+	if err != nil {
+		return err
+	}
+
+	for ; reserve < s.config.BankTargetSets; reserve++ {
+		select {
+		case <-s.stopChan:
+			return fmt.Errorf("scheduler stopped with %d of %d bank sets", reserve, s.config.BankTargetSets)
+		default:
+		}
+
+		setID, err := newID()
+		if err != nil {
+			return err
+		}
+
+		puzzlePointers, err := s.generator.GenerateBankSet(setID, s.store)
+		if err != nil {
+			return fmt.Errorf("failed to generate bank set: %w", err)
+		}
+
+		puzzles := make([]models.BankPuzzle, 0, len(puzzlePointers))
+		for _, p := range puzzlePointers {
+			if p != nil {
+				puzzles = append(puzzles, *p)
+			}
+		}
+
+		if err := s.store.SaveBankSet(puzzles, s.config.BankAutoApprove); err != nil {
+			return err
+		}
+		log.Printf("Bank: added reserve set %s (%d of %d)", setID, reserve+1, s.config.BankTargetSets)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"backend/internal/store"
+)
+
+// BufferStatus describes how many days of puzzles are ready ahead of time
+type BufferStatus struct {
+	Today      string   `json:"today"`
+	TargetDays int      `json:"targetDays"` // Future days the generate job keeps ready
+	DepthDays  int      `json:"depthDays"`  // Consecutive future days (after today) that have puzzles
+	HasToday   bool     `json:"hasToday"`
+	Missing    []string `json:"missing"` // Dates from today through the target with no puzzles
+}
+
+// Healthy reports whether today's puzzles exist and the buffer is at its target depth
+func (b *BufferStatus) Healthy() bool {
+	return b.HasToday && b.DepthDays >= b.TargetDays
+}
+
+// BufferStatus reports the current look-ahead buffer in the publication timezone
+func (s *Scheduler) BufferStatus() (*BufferStatus, error) {
+	today := store.GetTodayDate()
+	last := addDays(today, s.config.BufferDays)
+
+	dates, err := s.store.ListPuzzleDates(today, last)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		exists[date] = true
+	}
+
+	status := &BufferStatus{
+		Today:      today,
+		TargetDays: s.config.BufferDays,
+		HasToday:   exists[today],
+		Missing:    []string{},
+	}
+
+	counting := true
+	for i := 0; i <= s.config.BufferDays; i++ {
+		date := addDays(today, i)
+		if !exists[date] {
+			status.Missing = append(status.Missing, date)
+			if i > 0 {
+				counting = false
+			}
+			continue
+		}
+		if i > 0 && counting {
+			status.DepthDays++
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,292 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed five-field cron expression: minute hour day-of-month month day-of-week
+// Fields support "*", single values, ranges ("1-5"), lists ("1,15") and steps ("*/15", "0-30/5").
+// Day-of-week is 0-6 with Sunday as 0 (7 is also accepted for Sunday). When both day-of-month and
+// day-of-week are restricted, a day matches if either does, as in standard cron.
+// Jobs with a restricted hour run once across DST changes: a time skipped when clocks go forward
+// runs at the change, and a time repeated when they go back only runs the first time.
+type CronSchedule struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	hourStar bool
+}
+
+// cronField describes the valid range of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteField = cronField{"minute", 0, 59}
+	hourField   = cronField{"hour", 0, 23}
+	domField    = cronField{"day of month", 1, 31}
+	monthField  = cronField{"month", 1, 12}
+	dowField    = cronField{"day of week", 0, 7}
+)
+
+// cronDescriptors are shorthand expressions for common schedules
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a five-field cron expression or a descriptor such as "@daily"
+func ParseCron(spec string) (*CronSchedule, error) {
+	expr := strings.TrimSpace(spec)
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &CronSchedule{spec: spec}
+	var err error
+	if s.minute, err = parseCronField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	if s.hour, err = parseCronField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	if s.dom, err = parseCronField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	if s.month, err = parseCronField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	if s.dow, err = parseCronField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+
+	// Sunday can be written as 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	s.hourStar = fields[1] == "*"
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *CronSchedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time strictly after t that matches the schedule, in t's location
+// Returns the zero time if nothing matches within five years (e.g. "0 0 30 2 *").
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	next := s.next(t)
+	if s.hourStar {
+		return next
+	}
+	if skipped := s.skippedRun(t, next); !skipped.IsZero() {
+		return skipped
+	}
+	return next
+}
+
+// next returns the first existing time strictly after t that matches the schedule
+func (s *CronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 || (!s.hourStar && repeatedWallTime(t)) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// skippedRun returns the first forward DST change after t, and no later than next, whose skipped
+// wall clock times include a match, or the zero time if there isn't one
+func (s *CronSchedule) skippedRun(t, next time.Time) time.Time {
+	if next.IsZero() {
+		next = t.AddDate(5, 0, 0)
+	}
+
+	for zone := t; ; {
+		_, change := zone.ZoneBounds()
+		if change.IsZero() || change.After(next) {
+			return time.Time{}
+		}
+		zone = change
+
+		_, before := change.Add(-time.Second).Zone()
+		_, after := change.Zone()
+		if after <= before {
+			continue
+		}
+
+		// Walk the skipped wall clock times as UTC, where they exist
+		end := wallClock(change)
+		for w := end.Add(-time.Duration(after-before) * time.Second); w.Before(end); w = w.Add(time.Minute) {
+			if s.matches(w) {
+				return change
+			}
+		}
+	}
+}
+
+// wallClock returns t's wall clock time to the minute, in UTC
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// repeatedWallTime reports whether t's wall clock time already happened before clocks went back
+func repeatedWallTime(t time.Time) bool {
+	start, _ := t.ZoneBounds()
+	if start.IsZero() {
+		return false
+	}
+	_, before := start.Add(-time.Second).Zone()
+	_, after := t.Zone()
+	return before > after && t.Sub(start) < time.Duration(before-after)*time.Second
+}
+
+// matches reports whether t's wall clock time matches every field
+func (s *CronSchedule) matches(t time.Time) bool {
+	return s.month&(1<<uint(t.Month())) != 0 &&
+		s.matchesDay(t) &&
+		s.hour&(1<<uint(t.Hour())) != 0 &&
+		s.minute&(1<<uint(t.Minute())) != 0
+}
+
+// advance returns next, or t plus one minute if a DST transition normalized next to a time not after t
+// (e.g. 02:00 on a spring-forward day can resolve to 01:00 standard time)
+func advance(t, next time.Time) time.Time {
+	if !next.After(t) {
+		return t.Add(time.Minute)
+	}
+	return next
+}
+
+// matchesDay applies the standard cron day-of-month / day-of-week rules
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dowMatch
+	case s.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// parseCronField parses one comma-separated cron field into a bitset
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parseCronRange(part, spec)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+// parseCronRange parses "*", "n", "a-b" with an optional "/step"
+func parseCronRange(part string, spec cronField) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepPart)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", stepPart, spec.name)
+		}
+		step = n
+	}
+
+	var start, end int
+	switch {
+	case rangePart == "*":
+		start, end = spec.min, spec.max
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = parseCronValue(lo, spec); err != nil {
+			return 0, err
+		}
+		if end, err = parseCronValue(hi, spec); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q in %s field", rangePart, spec.name)
+		}
+	default:
+		value, err := parseCronValue(rangePart, spec)
+		if err != nil {
+			return 0, err
+		}
+		start, end = value, value
+		// "5/15" means starting at 5, every 15
+		if hasStep {
+			end = spec.max
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a single number and checks it against the field's range
+func parseCronValue(value string, spec cronField) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, spec.name)
+	}
+	if n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d in %s field", n, spec.min, spec.max, spec.name)
+	}
+	return n, nil
+}
@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 * * * *", false},
+		{"0-30/5 9-17 * * 1-5", false},
+		{"5/15 * * * *", false},
+		{"0 0 1,15 * *", false},
+		{"0 0 * * 7", false},
+		{" @daily ", false},
+		{"@hourly", false},
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"*/x * * * *", true},
+		{"5-1 * * * *", true},
+		{"a * * * *", true},
+		{"1,,2 * * * *", true},
+		{"@every 5m", true},
+	}
+	for _, tt := range tests {
+		_, err := ParseCron(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCron(%q) error = %v, wantErr %t", tt.spec, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want []string
+	}{
+		// Steps and ranges
+		{"*/15 * * * *", "2025-01-15T10:07:00Z", []string{"2025-01-15T10:15:00Z", "2025-01-15T10:30:00Z", "2025-01-15T10:45:00Z", "2025-01-15T11:00:00Z"}},
+		{"5/20 * * * *", "2025-01-15T10:00:00Z", []string{"2025-01-15T10:05:00Z", "2025-01-15T10:25:00Z", "2025-01-15T10:45:00Z", "2025-01-15T11:05:00Z"}},
+		{"0-30/10 9-10 * * *", "2025-01-15T09:25:00Z", []string{"2025-01-15T09:30:00Z", "2025-01-15T10:00:00Z", "2025-01-15T10:10:00Z", "2025-01-15T10:20:00Z", "2025-01-15T10:30:00Z", "2025-01-16T09:00:00Z"}},
+		{"0 8 * * 1-5", "2025-01-17T09:00:00Z", []string{"2025-01-20T08:00:00Z", "2025-01-21T08:00:00Z"}},
+		{"0 0 1,15 * *", "2025-01-15T00:00:00Z", []string{"2025-02-01T00:00:00Z", "2025-02-15T00:00:00Z", "2025-03-01T00:00:00Z"}},
+
+		// Strictly after, ignoring seconds
+		{"30 6 * * *", "2025-01-15T06:30:00Z", []string{"2025-01-16T06:30:00Z"}},
+		{"30 6 * * *", "2025-01-15T06:29:59Z", []string{"2025-01-15T06:30:00Z"}},
+
+		// Day of month and day of week: either matches when both are restricted
+		{"0 0 13 * 5", "2025-06-01T00:00:00Z", []string{"2025-06-06T00:00:00Z", "2025-06-13T00:00:00Z", "2025-06-20T00:00:00Z", "2025-06-27T00:00:00Z", "2025-07-04T00:00:00Z"}},
+		{"0 0 13 * *", "2025-06-01T00:00:00Z", []string{"2025-06-13T00:00:00Z", "2025-07-13T00:00:00Z"}},
+		{"0 0 * * 5", "2025-06-13T00:00:00Z", []string{"2025-06-20T00:00:00Z"}},
+		{"0 0 * * 0", "2025-06-01T00:00:00Z", []string{"2025-06-08T00:00:00Z"}},
+		{"0 0 * * 7", "2025-06-01T00:00:00Z", []string{"2025-06-08T00:00:00Z"}},
+
+		// Month boundaries, short months and leap years
+		{"0 12 31 * *", "2025-01-31T12:00:00Z", []string{"2025-03-31T12:00:00Z", "2025-05-31T12:00:00Z"}},
+		{"0 0 29 2 *", "2025-01-01T00:00:00Z", []string{"2028-02-29T00:00:00Z"}},
+		{"@yearly", "2025-12-31T23:59:00Z", []string{"2026-01-01T00:00:00Z"}},
+		{"0 0 30 2 *", "2025-01-01T00:00:00Z", []string{"0001-01-01T00:00:00Z"}},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+		}
+		from := mustParseTime(t, tt.from)
+		for _, want := range tt.want {
+			got := schedule.Next(from)
+			if !got.Equal(mustParseTime(t, want)) {
+				t.Errorf("%q: Next(%s) = %s, want %s", tt.spec, from.Format(time.RFC3339), got.Format(time.RFC3339), want)
+				break
+			}
+			from = got
+		}
+	}
+}
+
+// TestCronNextDST runs schedules across New York's clock changes in 2025:
+// 02:00 EST jumped to 03:00 EDT on 9 March, and 02:00 EDT fell back to 01:00 EST on 2 November.
+func TestCronNextDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want []string
+	}{
+		{
+			"skipped time runs at the change",
+			"30 2 * * *", "2025-03-08T12:00:00-05:00",
+			[]string{"2025-03-09T03:00:00-04:00", "2025-03-10T02:30:00-04:00"},
+		},
+		{
+			"skipped time already past",
+			"30 2 * * *", "2025-03-09T03:00:00-04:00",
+			[]string{"2025-03-10T02:30:00-04:00"},
+		},
+		{
+			"time after the gap",
+			"30 3 * * *", "2025-03-08T12:00:00-05:00",
+			[]string{"2025-03-09T03:30:00-04:00", "2025-03-10T03:30:00-04:00"},
+		},
+		{
+			"midnight keeps its wall clock time",
+			"0 0 * * *", "2025-03-08T12:00:00-05:00",
+			[]string{"2025-03-09T00:00:00-05:00", "2025-03-10T00:00:00-04:00"},
+		},
+		{
+			"wildcard hour follows the clock forward",
+			"30 * * * *", "2025-03-09T01:00:00-05:00",
+			[]string{"2025-03-09T01:30:00-05:00", "2025-03-09T03:30:00-04:00"},
+		},
+		{
+			"repeated time runs once",
+			"30 1 * * *", "2025-11-01T12:00:00-04:00",
+			[]string{"2025-11-02T01:30:00-04:00", "2025-11-03T01:30:00-05:00"},
+		},
+		{
+			"repeated time from the second pass",
+			"30 1 * * *", "2025-11-02T01:10:00-05:00",
+			[]string{"2025-11-03T01:30:00-05:00"},
+		},
+		{
+			"time after the repeat",
+			"0 2 * * *", "2025-11-02T01:30:00-04:00",
+			[]string{"2025-11-02T02:00:00-05:00", "2025-11-03T02:00:00-05:00"},
+		},
+		{
+			"wildcard hour follows the clock back",
+			"*/30 * * * *", "2025-11-02T00:45:00-04:00",
+			[]string{"2025-11-02T01:00:00-04:00", "2025-11-02T01:30:00-04:00", "2025-11-02T01:00:00-05:00", "2025-11-02T01:30:00-05:00", "2025-11-02T02:00:00-05:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+			}
+			from := mustParseTime(t, tt.from).In(newYork)
+			for _, want := range tt.want {
+				got := schedule.Next(from)
+				if !got.Equal(mustParseTime(t, want)) {
+					t.Fatalf("Next(%s) = %s, want %s", from.Format(time.RFC3339), got.Format(time.RFC3339), want)
+				}
+				if got.Location() != newYork {
+					t.Errorf("Next(%s) is in %s, want America/New_York", from.Format(time.RFC3339), got.Location())
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("time.Parse(%q): %v", value, err)
+	}
+	return parsed
+}
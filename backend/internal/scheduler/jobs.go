@@ -0,0 +1,202 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// Built-in job names
+const (
+	JobGenerate  = "generate"
+	JobCleanup   = "cleanup"
+	JobStats     = "stats"
+	JobReminders = "reminders"
+	JobFallback  = "fallback"
+	JobBankTopup = "bank-topup"
+	JobPublish   = "publish"
+	JobImageGC   = "image-gc"
+)
+
+// statsLookbackDays is how many recent days the stats job recomputes
+const statsLookbackDays = 7
+
+// reminderLookaheadDays is how many upcoming days the reminders job checks
+const reminderLookaheadDays = 2
+
+// rateLimitRetention is how long an idle rate limit bucket is kept; forgotten buckets start full again
+const rateLimitRetention = 24 * time.Hour
+
+// runGenerateJob fills every missing date from today through today + BufferDays
+// Future dates stay hidden from players by the release policy until they're released.
+// A failed date doesn't stop the remaining dates from being generated. If today fails, a reserve
+// set is promoted from the bank; future dates are simply retried on the next run.
+func (s *Scheduler) runGenerateJob() error {
+	status, err := s.BufferStatus()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, date := range status.Missing {
+		select {
+		case <-s.stopChan:
+			return fmt.Errorf("scheduler stopped before generating %v", status.Missing)
+		default:
+		}
+
+		if _, err := s.generatePuzzlesForDate(date, false); err != nil {
+			// Another instance is already on it
+			if errors.Is(err, ErrGenerationInProgress) {
+				log.Printf("Skipping %s: %v", date, err)
+				continue
+			}
+			log.Printf("Error generating puzzles for %s: %v", date, err)
+			if date <= status.Today {
+				if promoted, err := s.promoteBankSet(date); err != nil {
+					log.Printf("Failed to promote a bank set for %s: %v", date, err)
+				} else if promoted {
+					continue
+				}
+			}
+			failed = append(failed, date)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to generate puzzles for %v", failed)
+	}
+	return nil
+}
+
+// runCleanupJob deletes idle rate limit buckets and player progress older than the retention period
+func (s *Scheduler) runCleanupJob() error {
+	rateLimitCutoff := time.Now().Add(-rateLimitRetention)
+	deletedBuckets, err := s.store.DeleteRateLimitsBefore(rateLimitCutoff)
+	if err != nil {
+		return err
+	}
+	if deletedBuckets > 0 {
+		log.Printf("Cleanup: deleted %d idle rate limit buckets", deletedBuckets)
+	}
+
+	if s.config.ProgressRetentionDays <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -s.config.ProgressRetentionDays)
+	deleted, err := s.store.DeletePlayerProgressBefore(cutoff)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Cleanup: deleted %d player progress rows older than %s", deleted, cutoff.Format("2006-01-02"))
+	return nil
+}
+
+// runStatsJob recomputes per-puzzle statistics for recent days
+// Older days rarely change, so only the last statsLookbackDays are rolled up each run.
+func (s *Scheduler) runStatsJob() error {
+	since := addDays(store.GetTodayDate(), -statsLookbackDays)
+	updated, err := s.store.RollupPuzzleStats(since)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Stats: rolled up statistics for %d puzzles since %s", updated, since)
+	return nil
+}
+
+// runRemindersJob logs a warning for each upcoming day that has no puzzles yet
+func (s *Scheduler) runRemindersJob() error {
+	today := store.GetTodayDate()
+
+	var missing []string
+	for i := 0; i <= reminderLookaheadDays; i++ {
+		date := addDays(today, i)
+		if !s.store.HasPuzzlesForDate(date) {
+			missing = append(missing, date)
+		}
+	}
+
+	// Puzzles stuck in review for upcoming days won't be shown to players
+	pending, err := s.store.ListPendingPuzzles()
+	if err != nil {
+		return err
+	}
+	lastDate := addDays(today, reminderLookaheadDays)
+	var unreviewed []string
+	for _, p := range pending {
+		if p.Date >= today && p.Date <= lastDate {
+			unreviewed = append(unreviewed, p.ID)
+		}
+	}
+	if len(unreviewed) > 0 {
+		log.Printf("REMINDER: puzzles awaiting review for upcoming days: %v", unreviewed)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.Printf("REMINDER: no puzzles generated yet for %v", missing)
+	return fmt.Errorf("%d upcoming day(s) have no puzzles", len(missing))
+}
+
+// runPublishJob marks approved puzzles as published once their date has been released
+func (s *Scheduler) runPublishJob() error {
+	through, err := s.config.ReleasePolicy.LatestReleasedDate(time.Now(), nil)
+	if err != nil {
+		return err
+	}
+
+	published, err := s.store.PublishApprovedPuzzles(through)
+	if err != nil {
+		return err
+	}
+
+	if published > 0 {
+		log.Printf("Publish: marked %d puzzle(s) through %s as published", published, through)
+	}
+	return nil
+}
+
+// runImageGCJob deletes or quarantines images that no puzzle or bank entry uses
+func (s *Scheduler) runImageGCJob() error {
+	report, err := s.store.CollectOrphanedImages(s.imageGCGrace(), s.config.ImageGCAction)
+	if err != nil {
+		return err
+	}
+
+	if len(report.Orphans) > 0 {
+		log.Printf("Image GC: %s %d orphaned image(s), %d bytes (%d scanned, %d within the grace period)",
+			report.Action, len(report.Orphans), report.OrphanBytes, report.Scanned, report.Recent)
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to %s %d orphaned image(s)", report.Action, len(report.Failed))
+	}
+	return nil
+}
+
+// FindOrphanedImages reports what the image GC job would remove now, without removing anything
+func (s *Scheduler) FindOrphanedImages() (*models.ImageGCReport, error) {
+	return s.store.CollectOrphanedImages(s.imageGCGrace(), models.ImageGCNone)
+}
+
+// imageGCGrace is how long the image GC job leaves an orphaned image alone
+func (s *Scheduler) imageGCGrace() time.Duration {
+	return time.Duration(s.config.ImageGCGraceHours) * time.Hour
+}
+
+// addDays offsets a YYYY-MM-DD date by n days
+func addDays(date string, n int) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, n).Format("2006-01-02")
+}
@@ -1,35 +1,153 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"backend/internal/ai"
 	"backend/internal/models"
+	"backend/internal/release"
 	"backend/internal/store"
 )
 
-// Scheduler handles daily batch jobs for puzzle generation
+// JobConfig configures one of the scheduler's built-in jobs
+type JobConfig struct {
+	Schedule string // Cron expression, evaluated in the publication timezone
+	Enabled  bool
+}
+
+// Config configures the scheduler's built-in jobs
+type Config struct {
+	Generate              JobConfig
+	Cleanup               JobConfig
+	Stats                 JobConfig
+	Reminders             JobConfig
+	Fallback              JobConfig // Promotes a bank set when today still has no puzzles
+	BankTopup             JobConfig
+	Publish               JobConfig // Marks approved puzzles as published once released
+	ImageGC               JobConfig // Removes images no puzzle or bank entry uses
+	BufferDays            int       // The generate job keeps today through today + BufferDays generated
+	ProgressRetentionDays int       // The cleanup job deletes player progress older than this
+	InstanceID            string    // Recorded as the lock holder in the jobs table
+	BackfillConcurrency   int       // Maximum dates a backfill generates at once
+	BackfillBudget        int       // Maximum dates a single backfill may generate (0 = unlimited)
+	BankTargetSets        int       // The bank top-up job keeps this many reserve sets (0 = disabled)
+	BankAutoApprove       bool      // Approve generated bank sets without an admin review
+	ReviewRequired        bool      // Generated puzzles wait in review instead of being approved
+	ImageGCGraceHours     int       // The image GC job leaves orphans modified more recently than this
+	ImageGCAction         string    // What the image GC job does with orphans: delete or quarantine
+	ReleasePolicy         *release.Policy
+}
+
+// ErrGenerationInProgress is returned when another instance is already generating a date
+var ErrGenerationInProgress = errors.New("puzzle generation is already in progress")
+
+// Job is a named task that runs on a cron schedule
+type Job struct {
+	Name        string
+	Description string
+	Schedule    *CronSchedule
+	Enabled     bool
+	Run         func() error
+
+	mu        sync.Mutex
+	running   bool
+	nextRun   time.Time
+	lastRun   time.Time
+	lastError string
+
+	lockHolder string // Instance holding the cluster-wide lock when this instance skipped a run
+}
+
+// JobInfo is a snapshot of a job's schedule and last result
+type JobInfo struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Schedule    string     `json:"schedule"`
+	Enabled     bool       `json:"enabled"`
+	Running     bool       `json:"running"`
+	LockHolder  string     `json:"lockHolder,omitempty"`
+	NextRun     *time.Time `json:"nextRun,omitempty"`
+	LastRun     *time.Time `json:"lastRun,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// Scheduler runs registered jobs on their cron schedules
 type Scheduler struct {
 	store     *store.Store
 	generator ai.AIGenerator
-	hour      int
-	minute    int
+	config    Config
+	jobs      []*Job
 	stopChan  chan struct{}
 	running   bool
+	wg        sync.WaitGroup
+
+	backfillsMu sync.Mutex
+	backfills   map[string]*Backfill
 }
 
-// NewScheduler creates a new scheduler
-func NewScheduler(store *store.Store, generator ai.AIGenerator, hour, minute int) *Scheduler {
-	return &Scheduler{
+// NewScheduler creates a new scheduler with the built-in jobs registered
+func NewScheduler(store *store.Store, generator ai.AIGenerator, config Config) (*Scheduler, error) {
+	s := &Scheduler{
 		store:     store,
 		generator: generator,
-		hour:      hour,
-		minute:    minute,
+		config:    config,
 		stopChan:  make(chan struct{}),
 		running:   false,
+		backfills: make(map[string]*Backfill),
+	}
+
+	builtins := []struct {
+		name        string
+		description string
+		job         JobConfig
+		run         func() error
+	}{
+		{JobGenerate, fmt.Sprintf("Fill missing puzzles for today through %d day(s) ahead", config.BufferDays), config.Generate, s.runGenerateJob},
+		{JobCleanup, fmt.Sprintf("Delete player progress older than %d days", config.ProgressRetentionDays), config.Cleanup, s.runCleanupJob},
+		{JobStats, "Roll up per-puzzle player statistics", config.Stats, s.runStatsJob},
+		{JobReminders, "Warn when upcoming days have no puzzles", config.Reminders, s.runRemindersJob},
+		{JobFallback, "Promote a reserve bank set if today still has no puzzles", config.Fallback, s.runFallbackJob},
+		{JobBankTopup, fmt.Sprintf("Keep %d reserve puzzle set(s) in the bank", config.BankTargetSets), config.BankTopup, s.runBankTopupJob},
+		{JobPublish, "Mark approved puzzles as published once their date is released", config.Publish, s.runPublishJob},
+		{JobImageGC, fmt.Sprintf("Clean up (%s) images no puzzle has used for %d hour(s)", config.ImageGCAction, config.ImageGCGraceHours), config.ImageGC, s.runImageGCJob},
+	}
+
+	for _, b := range builtins {
+		if err := s.Register(b.name, b.description, b.job.Schedule, b.job.Enabled, b.run); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Register adds a job to the scheduler
+// Jobs must be registered before Start.
+func (s *Scheduler) Register(name, description, spec string, enabled bool, run func() error) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return fmt.Errorf("job %s is already registered", name)
+		}
 	}
+
+	s.jobs = append(s.jobs, &Job{
+		Name:        name,
+		Description: description,
+		Schedule:    schedule,
+		Enabled:     enabled,
+		Run:         run,
+	})
+	return nil
 }
 
 // Start starts the scheduler
@@ -40,13 +158,31 @@ func (s *Scheduler) Start() {
 	}
 
 	s.running = true
-	log.Printf("Scheduler started. Will run daily at %02d:%02d", s.hour, s.minute)
+	for _, job := range s.jobs {
+		if !job.Enabled {
+			log.Printf("Scheduler: job %s is disabled", job.Name)
+			continue
+		}
+		log.Printf("Scheduler: job %s scheduled with %q", job.Name, job.Schedule)
+	}
 
-	// Run immediately if it's past the scheduled time today
-	s.checkAndRunIfNeeded()
+	// Fill any gaps in the buffer in the background so startup isn't blocked on generation
+	if job := s.job(JobGenerate); job != nil && job.Enabled {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			log.Println("Scheduler: filling puzzle buffer on startup")
+			s.execute(job)
+		}()
+	}
 
-	// Then schedule for daily execution
-	go s.run()
+	// Then run each enabled job on its schedule
+	for _, job := range s.jobs {
+		if job.Enabled {
+			s.wg.Add(1)
+			go s.run(job)
+		}
+	}
 }
 
 // Stop stops the scheduler
@@ -57,121 +193,217 @@ func (s *Scheduler) Stop() {
 
 	s.running = false
 	close(s.stopChan)
+	s.wg.Wait()
 	log.Println("Scheduler stopped")
 }
 
-// run runs the scheduler loop
-func (s *Scheduler) run() {
-	for {
-		// Calculate next run time
-		now := time.Now()
-		nextRun := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+// Jobs returns a snapshot of all registered jobs
+func (s *Scheduler) Jobs() []JobInfo {
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		job.mu.Lock()
+		info := JobInfo{
+			Name:        job.Name,
+			Description: job.Description,
+			Schedule:    job.Schedule.String(),
+			Enabled:     job.Enabled,
+			Running:     job.running,
+			LastError:   job.lastError,
+			LockHolder:  job.lockHolder,
+		}
+		if job.Enabled {
+			next := job.nextRun
+			if next.IsZero() {
+				next = job.Schedule.Next(time.Now().In(store.PublicationLocation()))
+			}
+			if !next.IsZero() {
+				info.NextRun = &next
+			}
+		}
+		if !job.lastRun.IsZero() {
+			lastRun := job.lastRun
+			info.LastRun = &lastRun
+		}
+		job.mu.Unlock()
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// run runs a job's scheduler loop
+func (s *Scheduler) run(job *Job) {
+	defer s.wg.Done()
 
-		// If the time has passed today, schedule for tomorrow
-		if nextRun.Before(now) {
-			nextRun = nextRun.Add(24 * time.Hour)
+	for {
+		// Calculate next run time in the publication timezone
+		now := time.Now().In(store.PublicationLocation())
+		nextRun := job.Schedule.Next(now)
+		if nextRun.IsZero() {
+			log.Printf("Scheduler: job %s has no upcoming run time, stopping it", job.Name)
+			return
 		}
 
+		job.mu.Lock()
+		job.nextRun = nextRun
+		job.mu.Unlock()
+
 		duration := nextRun.Sub(now)
-		log.Printf("Next batch job scheduled for: %s (in %v)", nextRun.Format("2006-01-02 15:04:05"), duration)
+		log.Printf("Next %s job scheduled for: %s (in %v)", job.Name, nextRun.Format("2006-01-02 15:04:05 MST"), duration)
 
 		// Wait until next run time or stop signal
+		timer := time.NewTimer(duration)
 		select {
-		case <-time.After(duration):
-			s.generatePuzzlesForToday()
+		case <-timer.C:
+			s.execute(job)
 		case <-s.stopChan:
+			timer.Stop()
 			return
 		}
 	}
 }
 
-// checkAndRunIfNeeded checks if we should run the job now
-func (s *Scheduler) checkAndRunIfNeeded() {
-	now := time.Now()
-	today := store.GetTodayDate()
-
-	// Check if puzzles already exist for today
-	if s.store.HasPuzzlesForDate(today) {
-		log.Printf("Puzzles already exist for today (%s), skipping generation", today)
+// execute runs a job once, skipping it if the previous run is still going here or on another instance
+func (s *Scheduler) execute(job *Job) {
+	job.mu.Lock()
+	if job.running {
+		job.mu.Unlock()
+		log.Printf("Scheduler: job %s is still running, skipping this run", job.Name)
 		return
 	}
-
-	// Check if it's past the scheduled time
-	scheduledTime := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
-	if now.After(scheduledTime) {
-		log.Printf("Scheduled time (%02d:%02d) has passed, generating puzzles for today", s.hour, s.minute)
-		s.generatePuzzlesForToday()
+	job.running = true
+	job.mu.Unlock()
+
+	// Every replica fires on the same schedule, so only the one holding the job's lock runs it
+	lock, err := s.store.TryLockJob(context.Background(), job.Name, s.config.InstanceID)
+	if err != nil || lock == nil {
+		holder := s.lockHolder(job.Name)
+
+		job.mu.Lock()
+		job.running = false
+		job.lockHolder = holder
+		job.mu.Unlock()
+
+		if err != nil {
+			log.Printf("Scheduler: failed to lock job %s, skipping this run: %v", job.Name, err)
+		} else {
+			log.Printf("Scheduler: job %s is running on %s, skipping this run", job.Name, holder)
+		}
+		return
 	}
-}
 
-// generatePuzzlesForToday generates puzzles for today's date
-func (s *Scheduler) generatePuzzlesForToday() {
-	today := store.GetTodayDate()
-	log.Printf("Starting batch job to generate puzzles for %s", today)
+	start := time.Now()
+	err = job.Run()
 
-	// Check if puzzles already exist
-	if s.store.HasPuzzlesForDate(today) {
-		log.Printf("Puzzles already exist for %s, skipping", today)
-		return
+	if releaseErr := lock.Release(err); releaseErr != nil {
+		log.Printf("Scheduler: failed to release lock for job %s: %v", job.Name, releaseErr)
 	}
 
-	if !s.store.HasPuzzlesForDate(today) {
-		log.Printf("Puzzles do not exist for %s, not generating", today)
-		return
+	job.mu.Lock()
+	job.running = false
+	job.lockHolder = ""
+	job.lastRun = start
+	job.lastError = ""
+	if err != nil {
+		job.lastError = err.Error()
 	}
-	// Generate all 5 puzzles at once using Claude API
-	// This will first call Claude to get 5 prompts, then generate images for each
-	puzzlePointers, err := s.generator.GenerateRebusPuzzles(today, s.store)
+	job.mu.Unlock()
+
 	if err != nil {
-		log.Printf("Error generating puzzles: %v", err)
+		log.Printf("Scheduler: job %s failed after %v: %v", job.Name, time.Since(start), err)
 		return
 	}
+	log.Printf("Scheduler: job %s finished in %v", job.Name, time.Since(start))
+}
 
-	// Convert pointers to values
-	puzzles := make([]models.Puzzle, len(puzzlePointers))
-	for i, p := range puzzlePointers {
-		if p != nil {
-			puzzles[i] = *p
+// job returns the registered job with the given name, or nil
+func (s *Scheduler) job(name string) *Job {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return job
 		}
 	}
+	return nil
+}
 
-	// Save puzzles to store
-	if err := s.store.SavePuzzles(today, puzzles); err != nil {
-		log.Printf("Error saving puzzles: %v", err)
-		return
+// lockHolder returns the instance holding a lock, for logging; "another instance" if it can't be looked up
+func (s *Scheduler) lockHolder(name string) string {
+	holder, err := s.store.GetJobLockHolder(name)
+	if err != nil || holder == "" {
+		return "another instance"
 	}
-
-	log.Printf("Successfully generated and saved 5 puzzles for %s", today)
+	return holder
 }
 
-// TriggerManualGeneration manually triggers puzzle generation for a specific date
-func (s *Scheduler) TriggerManualGeneration(date string) error {
-	if err := store.ValidateDate(date); err != nil {
-		return fmt.Errorf("invalid date: %w", err)
+// generatePuzzlesForDate generates and saves puzzles for a date
+// Dates that already have puzzles are left alone unless force is set. Returns whether puzzles were generated.
+// Each date has its own cluster-wide lock so manual triggers and scheduled runs on different
+// replicas never generate the same date twice. Returns ErrGenerationInProgress if the date is locked.
+func (s *Scheduler) generatePuzzlesForDate(date string, force bool) (generated bool, err error) {
+	lockName := JobGenerate + ":" + date
+	lock, err := s.store.TryLockJob(context.Background(), lockName, s.config.InstanceID)
+	if err != nil {
+		return false, err
+	}
+	if lock == nil {
+		return false, fmt.Errorf("%w for %s on %s", ErrGenerationInProgress, date, s.lockHolder(lockName))
 	}
+	defer func() {
+		if releaseErr := lock.Release(err); releaseErr != nil {
+			log.Printf("Failed to release generation lock for %s: %v", date, releaseErr)
+		}
+	}()
 
-	// Check if puzzles already exist
-	if s.store.HasPuzzlesForDate(date) {
-		return fmt.Errorf("puzzles already exist for date: %s", date)
+	log.Printf("Starting batch job to generate puzzles for %s", date)
+
+	// Check if puzzles already exist (another instance may have just finished them)
+	if !force && s.store.HasPuzzlesForDate(date) {
+		log.Printf("Puzzles already exist for %s, skipping", date)
+		return false, nil
 	}
 
 	// Generate all 5 puzzles at once using Claude API
 	// This will first call Claude to get 5 prompts, then generate images for each
 	puzzlePointers, err := s.generator.GenerateRebusPuzzles(date, s.store)
 	if err != nil {
-		return fmt.Errorf("failed to generate puzzles: %w", err)
+		return false, fmt.Errorf("failed to generate puzzles: %w", err)
+	}
+
+	// A bank set may have been promoted while generation was running; keep it rather than swap puzzles under players
+	if !force && s.store.HasPuzzlesForDate(date) {
+		log.Printf("Puzzles appeared for %s during generation, discarding the generated set", date)
+		return false, nil
 	}
 
 	// Convert pointers to values
+	status := models.PuzzleApproved
+	if s.config.ReviewRequired {
+		status = models.PuzzleInReview
+	}
 	puzzles := make([]models.Puzzle, len(puzzlePointers))
 	for i, p := range puzzlePointers {
 		if p != nil {
 			puzzles[i] = *p
+			puzzles[i].Status = status
 		}
 	}
 
-	// Save puzzles
-	return s.store.SavePuzzles(date, puzzles)
+	// Save puzzles to store
+	if err := s.store.SavePuzzles(date, puzzles); err != nil {
+		return false, fmt.Errorf("failed to save puzzles: %w", err)
+	}
+
+	log.Printf("Successfully generated and saved %d puzzles for %s", len(puzzles), date)
+	return true, nil
+}
+
+// TriggerManualGeneration manually triggers puzzle generation for a specific date
+// Returns true if puzzles were generated, false if they already existed and force wasn't set
+func (s *Scheduler) TriggerManualGeneration(date string, force bool) (bool, error) {
+	if err := store.ValidateDate(date); err != nil {
+		return false, fmt.Errorf("invalid date: %w", err)
+	}
+
+	return s.generatePuzzlesForDate(date, force)
 }
 
 // TriggerTodayGeneration triggers puzzle generation for today if puzzles don't exist
@@ -185,13 +417,18 @@ func (s *Scheduler) TriggerTodayGeneration() (bool, error) {
 		return false, nil
 	}
 
-	// Generate puzzles for today
-	s.generatePuzzlesForToday()
-
-	// Verify that puzzles were created
-	if !s.store.HasPuzzlesForDate(today) {
-		return false, fmt.Errorf("failed to generate puzzles for today")
+	// Generate puzzles for today, falling back to the bank so players aren't left without puzzles
+	generated, err := s.generatePuzzlesForDate(today, false)
+	if err != nil && !errors.Is(err, ErrGenerationInProgress) {
+		if promoted, promoteErr := s.promoteBankSet(today); promoteErr != nil {
+			log.Printf("Failed to promote a bank set for %s: %v", today, promoteErr)
+		} else if promoted {
+			return true, nil
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to generate puzzles for today: %w", err)
 	}
 
-	return true, nil
+	return generated, nil
 }
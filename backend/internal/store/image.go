@@ -0,0 +1,51 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"net/http"
+)
+
+// Limits on uploaded puzzle images
+const (
+	MinImageWidth  = 200
+	MinImageHeight = 150
+	MaxImageWidth  = 4096
+	MaxImageHeight = 4096
+)
+
+// uploadImageTypes are the content types accepted for uploaded images, by their decoder name
+var uploadImageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// ValidateImage checks an uploaded image's content type and dimensions from its header
+// Decoders for these formats are registered by the imageproc package.
+func ValidateImage(imageData []byte) error {
+	if len(imageData) == 0 {
+		return fmt.Errorf("image is empty")
+	}
+
+	contentType := http.DetectContentType(imageData)
+	format, ok := uploadImageTypes[contentType]
+	if !ok {
+		return fmt.Errorf("image must be a PNG, JPEG, GIF or WebP, got %s", contentType)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil || decodedFormat != format {
+		return fmt.Errorf("invalid %s image", format)
+	}
+	if config.Width < MinImageWidth || config.Height < MinImageHeight {
+		return fmt.Errorf("image must be at least %dx%d, got %dx%d", MinImageWidth, MinImageHeight, config.Width, config.Height)
+	}
+	if config.Width > MaxImageWidth || config.Height > MaxImageHeight {
+		return fmt.Errorf("image must be at most %dx%d, got %dx%d", MaxImageWidth, MaxImageHeight, config.Width, config.Height)
+	}
+
+	return nil
+}
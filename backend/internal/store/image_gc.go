@@ -0,0 +1,159 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// quarantineDir is where quarantined images are moved: a subdirectory of the images
+// directory, or a key prefix in Supabase; images in it aren't listed again
+const quarantineDir = "quarantine"
+
+// ListStoredImages lists the PNG and WebP images in the image store, leaving out quarantined ones
+func (s *Store) ListStoredImages() ([]models.StoredImage, error) {
+	if s.useSupabase {
+		objects, err := s.supabaseStorage.ListObjects("")
+		if err != nil {
+			return nil, err
+		}
+		images := make([]models.StoredImage, 0, len(objects))
+		for _, object := range objects {
+			if isImageFile(object.Path) && !strings.HasPrefix(object.Path, quarantineDir+"/") {
+				images = append(images, object)
+			}
+		}
+		return images, nil
+	}
+
+	entries, err := os.ReadDir(s.imagesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []models.StoredImage
+	for _, entry := range entries {
+		if entry.IsDir() || !isImageFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images: %w", err)
+		}
+		images = append(images, models.StoredImage{
+			Path:         filepath.Join(s.imagesPath, entry.Name()),
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+	}
+	return images, nil
+}
+
+// CollectOrphanedImages finds images that no puzzle or bank entry uses and, unless action is
+// models.ImageGCNone, deletes or quarantines those last modified more than grace ago
+// The grace period covers images saved by a generation that hasn't saved its puzzles yet.
+// Variants belong to the image they were made from (see models.ImageVariantPath).
+func (s *Store) CollectOrphanedImages(grace time.Duration, action string) (*models.ImageGCReport, error) {
+	switch action {
+	case models.ImageGCNone, models.ImageGCDelete, models.ImageGCQuarantine:
+	default:
+		return nil, fmt.Errorf("invalid image GC action %q: must be none, delete or quarantine", action)
+	}
+
+	// List the store first: an image saved after its puzzles are read can only be recent
+	images, err := s.ListStoredImages()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := s.db.ListImagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		referenced[s.imageName(p)] = true
+	}
+
+	report := &models.ImageGCReport{
+		Action:  action,
+		Grace:   grace.String(),
+		Scanned: len(images),
+		Orphans: []models.StoredImage{},
+	}
+	cutoff := time.Now().Add(-grace)
+	for _, image := range images {
+		if referenced[s.imageName(models.OriginalImagePath(image.Path))] {
+			report.Referenced++
+			continue
+		}
+		if image.LastModified.After(cutoff) {
+			report.Recent++
+			continue
+		}
+		report.Orphans = append(report.Orphans, image)
+		report.OrphanBytes += image.Size
+	}
+
+	if action == models.ImageGCNone {
+		return report, nil
+	}
+	for _, image := range report.Orphans {
+		if err := s.removeStoredImage(image.Path, action == models.ImageGCQuarantine); err != nil {
+			log.Printf("Image GC: failed to %s %s: %v", action, image.Path, err)
+			report.Failed = append(report.Failed, image.Path)
+		}
+	}
+	return report, nil
+}
+
+// removeStoredImage deletes an image, or moves it into quarantineDir
+func (s *Store) removeStoredImage(imagePath string, quarantine bool) error {
+	if s.useSupabase {
+		if quarantine {
+			return s.supabaseStorage.MoveObject(imagePath, quarantineDir+"/"+imagePath)
+		}
+		return s.supabaseStorage.DeleteObject(imagePath)
+	}
+
+	if !quarantine {
+		if err := os.Remove(imagePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete image: %w", err)
+		}
+		return nil
+	}
+	dir := filepath.Join(s.imagesPath, quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if err := os.Rename(imagePath, filepath.Join(dir, filepath.Base(imagePath))); err != nil {
+		return fmt.Errorf("failed to quarantine image: %w", err)
+	}
+	return nil
+}
+
+// imageName normalizes an image_path for comparison with listed images
+// Files are compared by name, so paths recorded under a different IMAGES_PATH still match.
+func (s *Store) imageName(imagePath string) string {
+	if s.useSupabase {
+		return imagePath
+	}
+	return filepath.Base(imagePath)
+}
+
+// isImageFile reports whether a name has an extension the image store writes
+func isImageFile(name string) bool {
+	switch path.Ext(name) {
+	case ".png", ".webp":
+		return true
+	}
+	return false
+}
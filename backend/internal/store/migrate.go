@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/imageproc"
+	"backend/internal/models"
+)
+
+// MigrateImages copies every image a puzzle or bank entry uses from src's storage backend to dst's,
+// and repoints the rows at the copies
+// Each image is copied with its variants, every copy is read back and checked against the
+// source's SHA-256, and only then are the image's rows updated, in one transaction. The rows
+// record progress, so an interrupted migration picks up where it stopped when run again.
+// Source files are left in place. With dryRun nothing is copied or updated.
+func MigrateImages(ctx context.Context, src, dst *Store, dryRun bool, progress func(models.ImageMigrationResult)) ([]models.ImageMigrationResult, error) {
+	if src.useSupabase == dst.useSupabase {
+		return nil, fmt.Errorf("source and destination use the same storage backend")
+	}
+
+	paths, err := dst.db.ListImagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ImageMigrationResult, 0, len(paths))
+	for _, oldPath := range paths {
+		result := models.ImageMigrationResult{OldPath: oldPath}
+		if ctx.Err() != nil {
+			result.Status = models.ImageMigrationCancelled
+		} else {
+			migrateImage(src, dst, dryRun, &result)
+		}
+		results = append(results, result)
+		if progress != nil {
+			progress(result)
+		}
+	}
+
+	return results, nil
+}
+
+// migrateImage moves one image and its variants, filling in result
+func migrateImage(src, dst *Store, dryRun bool, result *models.ImageMigrationResult) {
+	oldPath := result.OldPath
+	data, err := src.readImageFile(oldPath)
+	if errors.Is(err, ErrObjectNotFound) {
+		// Rows already moved in an earlier run point at an image the destination has
+		if _, err := dst.readImageFile(oldPath); err == nil {
+			result.Status = models.ImageAlreadyMigrated
+		} else {
+			result.Status = models.ImageMissing
+		}
+		return
+	}
+	if err != nil {
+		result.Status = models.ImageMigrationFailed
+		result.Error = err.Error()
+		return
+	}
+
+	newPath := dst.migratedImagePath(src.imageName(oldPath))
+	result.NewPath = newPath
+	if dryRun {
+		result.Status = models.ImageWouldMigrate
+		return
+	}
+
+	// Variants first and the image itself last, like saveImageWithVariants
+	files := [][2]string{}
+	for _, variant := range imageproc.DefaultVariants {
+		for _, format := range []string{"png", "webp"} {
+			if variant.Name == models.VariantFull && format == "png" {
+				continue
+			}
+			files = append(files, [2]string{
+				models.ImageVariantPath(oldPath, variant.Name, format),
+				models.ImageVariantPath(newPath, variant.Name, format),
+			})
+		}
+	}
+
+	for _, file := range files {
+		variantData, err := src.readImageFile(file[0])
+		if errors.Is(err, ErrObjectNotFound) {
+			continue // Images saved before variants existed don't have them
+		}
+		if err == nil {
+			err = copyImageFile(dst, file[1], variantData)
+		}
+		if err != nil {
+			result.Status = models.ImageMigrationFailed
+			result.Error = err.Error()
+			return
+		}
+		result.Files++
+		result.Bytes += int64(len(variantData))
+	}
+
+	if err := copyImageFile(dst, newPath, data); err != nil {
+		result.Status = models.ImageMigrationFailed
+		result.Error = err.Error()
+		return
+	}
+	result.Files++
+	result.Bytes += int64(len(data))
+
+	rows, err := dst.db.RepointImage(oldPath, newPath, dst.imageURLForPath(newPath))
+	if err != nil {
+		result.Status = models.ImageMigrationFailed
+		result.Error = err.Error()
+		return
+	}
+	result.Rows = rows
+	result.Status = models.ImageMigrated
+}
+
+// copyImageFile writes data to the store unless an identical copy is already there,
+// then reads it back to check its checksum
+func copyImageFile(s *Store, imagePath string, data []byte) error {
+	sum := sha256.Sum256(data)
+
+	existing, err := s.readImageFile(imagePath)
+	if err == nil && sha256.Sum256(existing) == sum {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return err
+	}
+
+	if err := s.writeImageFile(imagePath, data); err != nil {
+		return err
+	}
+
+	written, err := s.readImageFile(imagePath)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", imagePath, err)
+	}
+	if sha256.Sum256(written) != sum {
+		return fmt.Errorf("checksum mismatch for %s", imagePath)
+	}
+	return nil
+}
+
+// readImageFile reads an image from an S3 key or file path, returning ErrObjectNotFound if it doesn't
+// exist and ErrImageCorrupt if it doesn't match its content hash
+// File paths are looked up by name in the images directory, like the image GC job compares them.
+func (s *Store) readImageFile(imagePath string) ([]byte, error) {
+	var data []byte
+	if s.useSupabase {
+		object, err := s.supabaseStorage.GetObject(imagePath)
+		if err != nil {
+			return nil, err
+		}
+		data = object.Data
+	} else {
+		var err error
+		data, err = os.ReadFile(filepath.Join(s.imagesPath, filepath.Base(imagePath)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+	}
+
+	if err := VerifyImage(imagePath, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// migratedImagePath returns where an image named name in another backend is stored in this one
+// Names map between the backends' conventions: {date}-{rest} files become {date}/{rest} keys,
+// bank-{rest} files become bank/{rest} keys, sha256-{hash} files become sha256/{hash} keys,
+// and keys become files by replacing "/" with "-".
+func (s *Store) migratedImagePath(name string) string {
+	if !s.useSupabase {
+		return filepath.Join(s.imagesPath, strings.ReplaceAll(name, "/", "-"))
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if len(base) > 11 && ValidateDate(base[:10]) == nil && base[10] == '-' {
+		base = base[:10] + "/" + base[11:]
+	} else if rest, ok := strings.CutPrefix(base, "bank-"); ok {
+		base = "bank/" + rest
+	} else if rest, ok := strings.CutPrefix(base, "sha256-"); ok {
+		// Content hashes can't be guessed, and a token would hide them
+		return "sha256/" + rest + ext
+	}
+	return s.supabaseStorage.privateKey(base, ext)
+}
+
+// imageURLForPath returns the URL clients load an image stored at an S3 key or file path from
+func (s *Store) imageURLForPath(imagePath string) string {
+	if s.useSupabase {
+		return s.supabaseStorage.ObjectURL(imagePath)
+	}
+	return "/api/images/" + filepath.Base(imagePath)
+}
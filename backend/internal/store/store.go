@@ -1,22 +1,38 @@
 package store
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"backend/internal/database"
+	"backend/internal/imageproc"
 	"backend/internal/models"
 )
 
+// Errors returned by puzzle edits
+var (
+	ErrPuzzleNotFound       = database.ErrPuzzleNotFound
+	ErrInvalidOrder         = database.ErrInvalidOrder
+	ErrPuzzleExists         = database.ErrPuzzleExists
+	ErrImageVersionNotFound = database.ErrImageVersionNotFound
+)
+
 // Store handles puzzle storage with PostgreSQL for metadata and Supabase S3 or file system for images
 type Store struct {
 	db              *database.DB
 	imagesPath      string
 	supabaseStorage *SupabaseStorage
 	useSupabase     bool
+	imageOptions    imageproc.Options // How images are normalized before they're saved
 }
 
 // NewStore creates a new store instance with file system storage
@@ -27,9 +43,10 @@ func NewStore(db *database.DB, imagesPath string) (*Store, error) {
 	}
 
 	return &Store{
-		db:          db,
-		imagesPath:  imagesPath,
-		useSupabase: false,
+		db:           db,
+		imagesPath:   imagesPath,
+		useSupabase:  false,
+		imageOptions: imageproc.DefaultOptions(),
 	}, nil
 }
 
@@ -44,20 +61,97 @@ func NewStoreWithSupabase(db *database.DB, bucketName, region, accessKey, secret
 		db:              db,
 		supabaseStorage: supabaseStorage,
 		useSupabase:     true,
+		imageOptions:    imageproc.DefaultOptions(),
 	}, nil
 }
 
+// SupabaseStorage returns the Supabase storage images are kept in, or nil when they're on the file system
+func (s *Store) SupabaseStorage() *SupabaseStorage {
+	return s.supabaseStorage
+}
+
 // GetPuzzlesForDate returns puzzles for a specific date
 func (s *Store) GetPuzzlesForDate(date string) ([]models.Puzzle, error) {
 	return s.db.GetPuzzlesForDate(date)
 }
 
-// SavePuzzles saves puzzles for a date
+// GetPlayablePuzzlesForDate returns the puzzles for a date that have passed review
+func (s *Store) GetPlayablePuzzlesForDate(date string) ([]models.Puzzle, error) {
+	return s.db.GetPlayablePuzzlesForDate(date)
+}
+
+// GetPlayableImageDate returns the earliest date of a playable puzzle that uses an image
+func (s *Store) GetPlayableImageDate(imagePath string) (string, bool, error) {
+	return s.db.GetPlayableImageDate(imagePath)
+}
+
+// ListPendingPuzzles returns draft and in-review puzzles, oldest date first
+func (s *Store) ListPendingPuzzles() ([]models.Puzzle, error) {
+	return s.db.ListPendingPuzzles()
+}
+
+// ReviewPuzzle moves a puzzle to a new review status if it's currently in one of from, or returns nil
+func (s *Store) ReviewPuzzle(id, status, notes, reviewer string, from []string) (*models.Puzzle, error) {
+	return s.db.ReviewPuzzle(id, status, notes, reviewer, from)
+}
+
+// UpdatePuzzle applies edit to a puzzle and records the change in the audit table
+func (s *Store) UpdatePuzzle(id, action, actor string, edit func(p *models.Puzzle) error) (*models.Puzzle, error) {
+	return s.db.UpdatePuzzle(id, action, actor, edit)
+}
+
+// CreatePuzzle inserts a new puzzle, recording it in the audit trail; fails with ErrPuzzleExists if the slot is taken
+func (s *Store) CreatePuzzle(puzzle *models.Puzzle, actor string) error {
+	return s.db.CreatePuzzle(puzzle, actor)
+}
+
+// DeletePuzzle deletes a puzzle and its player progress and image versions, recording it in the audit table
+func (s *Store) DeletePuzzle(id, actor string) error {
+	return s.db.DeletePuzzle(id, actor)
+}
+
+// ReorderPuzzles renumbers a day's puzzles in the given order, rewriting their IDs to match
+func (s *Store) ReorderPuzzles(date string, ids []string, actor string) ([]models.Puzzle, error) {
+	return s.db.ReorderPuzzles(date, ids, actor)
+}
+
+// ListPuzzleAudit returns a puzzle's audit history, newest first
+func (s *Store) ListPuzzleAudit(puzzleID string) ([]models.PuzzleAuditEntry, error) {
+	return s.db.ListPuzzleAudit(puzzleID)
+}
+
+// ListPuzzleImageVersions returns every image a puzzle has used, newest first
+func (s *Store) ListPuzzleImageVersions(puzzleID string) ([]models.PuzzleImageVersion, error) {
+	return s.db.ListPuzzleImageVersions(puzzleID)
+}
+
+// RollbackPuzzleImage points a puzzle back at one of its earlier images, recording it in the audit table
+// Fails with ErrImageVersionNotFound if versionID isn't one of the puzzle's versions.
+func (s *Store) RollbackPuzzleImage(puzzleID string, versionID int64, actor string) (*models.Puzzle, error) {
+	version, err := s.db.GetPuzzleImageVersion(puzzleID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.db.UpdatePuzzle(puzzleID, models.AuditRollback, actor, func(p *models.Puzzle) error {
+		p.ImageURL = version.ImageURL
+		p.ImagePath = version.ImagePath
+		p.ImageMeta = version.ImageMeta
+		return nil
+	})
+}
+
+// PublishApprovedPuzzles marks approved puzzles dated on or before through as published
+func (s *Store) PublishApprovedPuzzles(through string) (int64, error) {
+	return s.db.PublishApprovedPuzzles(through)
+}
+
+// SavePuzzles saves puzzles for a date, replacing its existing puzzles apart from drafts
 func (s *Store) SavePuzzles(date string, puzzles []models.Puzzle) error {
 	return s.db.SavePuzzles(date, puzzles)
 }
 
-// HasPuzzlesForDate checks if puzzles exist for a date
+// HasPuzzlesForDate checks if puzzles that aren't rejected or drafts exist for a date
 func (s *Store) HasPuzzlesForDate(date string) bool {
 	exists, err := s.db.HasPuzzlesForDate(date)
 	if err != nil {
@@ -66,37 +160,288 @@ func (s *Store) HasPuzzlesForDate(date string) bool {
 	return exists
 }
 
-// GetImagePath returns the full path where an image should be stored
-func (s *Store) GetImagePath(date string, index int) string {
-	if s.useSupabase {
-		return s.supabaseStorage.GetImagePath(date, index)
+// HasPlayablePuzzlesForDate checks if a date has puzzles that have passed review
+func (s *Store) HasPlayablePuzzlesForDate(date string) bool {
+	exists, err := s.db.HasPlayablePuzzlesForDate(date)
+	if err != nil {
+		return false
+	}
+	return exists
+}
+
+// ListArchiveDays returns per-day puzzle summaries matching the filter, newest first
+func (s *Store) ListArchiveDays(filter models.ArchiveFilter) ([]models.ArchiveDay, error) {
+	return s.db.ListArchiveDays(filter)
+}
+
+// ListPuzzleDates returns the dates between from and to (inclusive) that have puzzles that aren't rejected or drafts
+func (s *Store) ListPuzzleDates(from, to string) ([]string, error) {
+	return s.db.ListPuzzleDates(from, to)
+}
+
+// GetPuzzleByID returns a single puzzle by its ID
+func (s *Store) GetPuzzleByID(id string) (*models.Puzzle, error) {
+	return s.db.GetPuzzleByID(id)
+}
+
+// GetPlayerProgress returns a player's progress on a puzzle
+func (s *Store) GetPlayerProgress(playerID, puzzleID string) (*models.PlayerProgress, error) {
+	return s.db.GetPlayerProgress(playerID, puzzleID)
+}
+
+// RecordAttempt records a player's guess on a puzzle, enforcing maxAttempts (0 = unlimited)
+func (s *Store) RecordAttempt(playerID, puzzleID string, correct bool, maxAttempts int) (*models.PlayerProgress, bool, error) {
+	return s.db.RecordAttempt(playerID, puzzleID, correct, maxAttempts)
+}
+
+// MarkPuzzleFailed marks a puzzle as given up by a player
+func (s *Store) MarkPuzzleFailed(playerID, puzzleID string) (*models.PlayerProgress, error) {
+	return s.db.MarkPuzzleFailed(playerID, puzzleID)
+}
+
+// DeletePlayerProgressBefore deletes player progress last updated before cutoff
+func (s *Store) DeletePlayerProgressBefore(cutoff time.Time) (int64, error) {
+	return s.db.DeletePlayerProgressBefore(cutoff)
+}
+
+// RollupPuzzleStats recomputes player statistics for puzzles on or after since
+func (s *Store) RollupPuzzleStats(since string) (int64, error) {
+	return s.db.RollupPuzzleStats(since)
+}
+
+// TryLockJob takes a cluster-wide advisory lock for a job, or returns nil if another instance holds it
+func (s *Store) TryLockJob(ctx context.Context, name, holder string) (*database.JobLock, error) {
+	return s.db.TryLockJob(ctx, name, holder)
+}
+
+// GetJobLockHolder returns the instance currently holding a job's lock, or ""
+func (s *Store) GetJobLockHolder(name string) (string, error) {
+	return s.db.GetJobLockHolder(name)
+}
+
+// SaveBankSet stores a new set of reserve bank puzzles
+func (s *Store) SaveBankSet(puzzles []models.BankPuzzle, approved bool) error {
+	return s.db.SaveBankSet(puzzles, approved)
+}
+
+// CountReserveBankSets counts bank sets that haven't been promoted yet
+func (s *Store) CountReserveBankSets() (int, error) {
+	return s.db.CountReserveBankSets()
+}
+
+// ListBankSets lists bank sets, optionally including ones already promoted
+func (s *Store) ListBankSets(includeUsed bool) ([]models.BankSet, error) {
+	return s.db.ListBankSets(includeUsed)
+}
+
+// ApproveBankSet approves a reserve set; returns false if no unused set has that ID
+func (s *Store) ApproveBankSet(setID string) (bool, error) {
+	return s.db.ApproveBankSet(setID)
+}
+
+// ClaimBankSet marks the oldest approved reserve set as used on a date, or returns nil if none are left
+func (s *Store) ClaimBankSet(date string) ([]models.BankPuzzle, error) {
+	return s.db.ClaimBankSet(date)
+}
+
+// UnclaimBankSet returns a claimed set to the reserve
+func (s *Store) UnclaimBankSet(setID string) error {
+	return s.db.UnclaimBankSet(setID)
+}
+
+// CreateAPIToken stores a new API token by its hash
+func (s *Store) CreateAPIToken(name, role, tokenHash, createdBy string) (*models.APIToken, error) {
+	return s.db.CreateAPIToken(name, role, tokenHash, createdBy)
+}
+
+// GetAPITokenByHash returns the unrevoked token with the given hash, or nil
+func (s *Store) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	return s.db.GetAPITokenByHash(tokenHash)
+}
+
+// TouchAPIToken records that a token was used
+func (s *Store) TouchAPIToken(id int) error {
+	return s.db.TouchAPIToken(id)
+}
+
+// ListAPITokens returns all API tokens, newest first
+func (s *Store) ListAPITokens() ([]models.APIToken, error) {
+	return s.db.ListAPITokens()
+}
+
+// RevokeAPIToken revokes a token; returns false if no unrevoked token has that ID
+func (s *Store) RevokeAPIToken(id int) (bool, error) {
+	return s.db.RevokeAPIToken(id)
+}
+
+// UpdateRateLimitBucket applies update to a token bucket while holding its row lock
+func (s *Store) UpdateRateLimitBucket(key string, capacity float64, update func(b *models.RateLimitBucket)) error {
+	return s.db.UpdateRateLimitBucket(key, capacity, update)
+}
+
+// DeleteRateLimitsBefore removes rate limit buckets that haven't been used since cutoff
+func (s *Store) DeleteRateLimitsBefore(cutoff time.Time) (int64, error) {
+	return s.db.DeleteRateLimitsBefore(cutoff)
+}
+
+// GetReleaseTime returns the release time override for a date, if any
+func (s *Store) GetReleaseTime(date string) (time.Time, bool, error) {
+	return s.db.GetReleaseTime(date)
+}
+
+// SetReleaseTime overrides the release time for a date
+func (s *Store) SetReleaseTime(date string, releaseAt time.Time) error {
+	return s.db.SetReleaseTime(date, releaseAt)
+}
+
+// DeleteReleaseTime removes a date's release time override
+func (s *Store) DeleteReleaseTime(date string) error {
+	return s.db.DeleteReleaseTime(date)
+}
+
+// SetImageOptions changes how images are normalized before they're saved
+// Call once at startup, before the scheduler and handlers are running.
+func (s *Store) SetImageOptions(opts imageproc.Options) {
+	s.imageOptions = opts
+}
+
+// ProcessImage runs image data through the processing pipeline, returning the canonical PNG to store
+func (s *Store) ProcessImage(imageData []byte) (*imageproc.Result, error) {
+	result, err := imageproc.Process(imageData, s.imageOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process image: %w", err)
+	}
+	if result.SourceFormat != "png" || result.Inverted {
+		log.Printf("Normalized %s image to %dx%d PNG (inverted: %t)", result.SourceFormat, result.Width, result.Height, result.Inverted)
 	}
-	filename := fmt.Sprintf("%s-%d.png", date, index)
-	return filepath.Join(s.imagesPath, filename)
+	return result, nil
 }
 
-// GetImageURL returns the URL path for an image
-func (s *Store) GetImageURL(date string, index int) string {
+// SaveImage processes image data and saves the result and its variants to disk or Supabase S3
+// Images are stored under the SHA-256 of their canonical PNG (see ContentHash), so saving
+// never overwrites another image: a regenerated or replaced image gets a new path and URL, and
+// the one it replaces stays in place and is kept as a version of its puzzle for rollback.
+func (s *Store) SaveImage(imageData []byte) (imageURL, imagePath string, meta models.ImageMeta, err error) {
+	result, err := s.ProcessImage(imageData)
+	if err != nil {
+		return "", "", models.ImageMeta{}, err
+	}
+
+	sum := sha256.Sum256(result.Data)
+	hash := hex.EncodeToString(sum[:])
 	if s.useSupabase {
-		return s.supabaseStorage.GetImageURL(date, index)
+		imagePath = "sha256/" + hash + ".png"
+	} else {
+		imagePath = filepath.Join(s.imagesPath, "sha256-"+hash+".png")
+	}
+	imageURL = s.imageURLForPath(imagePath)
+	log.Printf("Saving image to %s", imagePath)
+
+	meta, err = s.saveImageWithVariants(imagePath, result)
+	if err != nil {
+		return "", "", models.ImageMeta{}, err
+	}
+	meta.ImageSHA256 = hash
+	meta.ImageSize = int64(len(result.Data))
+	return imageURL, imagePath, meta, nil
+}
+
+// ErrImageCorrupt is returned when an image's data doesn't match the SHA-256 it's stored under
+var ErrImageCorrupt = errors.New("image data doesn't match its checksum")
+
+// contentImagePattern matches the canonical PNG of a content-addressed image at the end of a
+// path, key or URL: sha256-{hash}.png on disk and sha256/{hash}.png in Supabase
+var contentImagePattern = regexp.MustCompile(`(?:^|/)sha256[-/]([0-9a-f]{64})\.png$`)
+
+// ContentHash returns the SHA-256 a content-addressed image is stored under
+// Variants and images saved before content addressing have no hash in their name.
+func ContentHash(imagePath string) (string, bool) {
+	match := contentImagePattern.FindStringSubmatch(imagePath)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// VerifyImage checks image data against the SHA-256 its path names, returning ErrImageCorrupt on a mismatch
+// Paths without a hash (see ContentHash) always pass.
+func VerifyImage(imagePath string, data []byte) error {
+	hash, ok := ContentHash(imagePath)
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hash {
+		return fmt.Errorf("%s: %w", imagePath, ErrImageCorrupt)
+	}
+	return nil
+}
+
+// saveImageWithVariants writes a processed image's canonical PNG to imagePath, an S3 key
+// or file path, with each variant's PNG and WebP beside it (see models.ImageVariantPath)
+// The canonical PNG is written last, so an image that exists always has its variants.
+func (s *Store) saveImageWithVariants(imagePath string, result *imageproc.Result) (models.ImageMeta, error) {
+	renditions, err := imageproc.Renditions(result.Data, imageproc.DefaultVariants)
+	if err != nil {
+		return models.ImageMeta{}, fmt.Errorf("failed to render image variants: %w", err)
+	}
+
+	meta := models.ImageMeta{
+		ImageVariants: make([]models.ImageVariant, 0, len(renditions)),
+		BlurHash:      result.BlurHash,
+		DominantColor: result.DominantColor,
 	}
-	return fmt.Sprintf("/api/images/%s-%d.png", date, index)
+	for _, r := range renditions {
+		if r.Name != models.VariantFull {
+			if err := s.writeImageFile(models.ImageVariantPath(imagePath, r.Name, "png"), r.PNG); err != nil {
+				return models.ImageMeta{}, err
+			}
+		}
+		if err := s.writeImageFile(models.ImageVariantPath(imagePath, r.Name, "webp"), r.WebP); err != nil {
+			return models.ImageMeta{}, err
+		}
+		meta.ImageVariants = append(meta.ImageVariants, models.ImageVariant{Name: r.Name, Width: r.Width, Height: r.Height})
+	}
+
+	if err := s.writeImageFile(imagePath, result.Data); err != nil {
+		return models.ImageMeta{}, err
+	}
+	return meta, nil
 }
 
-// SaveImage saves image data to disk or Supabase S3
-func (s *Store) SaveImage(date string, index int, imageData []byte) error {
+// writeImageFile writes image data to an S3 key or file path
+func (s *Store) writeImageFile(imagePath string, data []byte) error {
 	if s.useSupabase {
-		fmt.Printf("Saving image to Supabase S3: %s/%d.png\n", date, index)
-		return s.supabaseStorage.SaveImage(date, index, imageData)
+		return s.supabaseStorage.SaveObject(imagePath, data)
+	}
+	if err := os.WriteFile(imagePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
 	}
-	imagePath := s.GetImagePath(date, index)
-	fmt.Println("Saving image to:", imagePath)
-	return os.WriteFile(imagePath, imageData, 0644)
+	return nil
+}
+
+// publicationLocation is the timezone that defines the puzzle day
+var publicationLocation = time.Local
+
+// SetPublicationLocation sets the timezone that defines the puzzle day
+// Call once at startup, before the scheduler and handlers are running.
+func SetPublicationLocation(loc *time.Location) {
+	publicationLocation = loc
 }
 
-// GetTodayDate returns today's date in YYYY-MM-DD format
+// PublicationLocation returns the timezone that defines the puzzle day
+func PublicationLocation() *time.Location {
+	return publicationLocation
+}
+
+// GetTodayDate returns today's date in the publication timezone in YYYY-MM-DD format
 func GetTodayDate() string {
-	return time.Now().Format("2006-01-02")
+	return GetTodayDateIn(publicationLocation)
+}
+
+// GetTodayDateIn returns today's date in the given timezone in YYYY-MM-DD format
+func GetTodayDateIn(loc *time.Location) string {
+	return time.Now().In(loc).Format("2006-01-02")
 }
 
 // ValidateDate validates date format (YYYY-MM-DD)
@@ -2,14 +2,24 @@ package store
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+
+	"backend/internal/models"
 )
 
 // SupabaseStorage handles image storage using Supabase S3-compatible storage
@@ -18,6 +28,8 @@ type SupabaseStorage struct {
 	bucketName  string
 	publicURL   string
 	region      string
+	private     bool   // Objects are uploaded without a public-read ACL and get non-guessable keys
+	keySecret   []byte // Keys the tokens in private object keys
 }
 
 // NewSupabaseStorage creates a new Supabase storage instance
@@ -46,74 +58,169 @@ func NewSupabaseStorage(bucketName, region, accessKey, secretKey, endpoint, publ
 }
 
 // SaveImage saves image data to Supabase S3 bucket in a date-wise folder
-// Path format: {date}/{index}.png
+// Path format: {date}/{index}.png, or {date}/{index}-{token}.png for a private bucket
 func (s *SupabaseStorage) SaveImage(date string, index int, imageData []byte) error {
-	// Create path: date/index.png (e.g., "2025-12-13/0.png")
-	key := fmt.Sprintf("%s/%d.png", date, index)
+	return s.SaveObject(s.GetImagePath(date, index), imageData)
+}
+
+// GetImageURL returns the public URL for an image stored in Supabase
+// URL format: {publicURL}/{date}/{index}.png
+func (s *SupabaseStorage) GetImageURL(date string, index int) string {
+	return s.ObjectURL(s.GetImagePath(date, index))
+}
+
+// GetImagePath returns the S3 key path for an image (for reference)
+func (s *SupabaseStorage) GetImagePath(date string, index int) string {
+	return s.privateKey(fmt.Sprintf("%s/%d", date, index), ".png")
+}
+
+// SaveObject uploads a PNG or WebP image under an arbitrary key
+func (s *SupabaseStorage) SaveObject(key string, imageData []byte) error {
+	contentType := "image/png"
+	if path.Ext(key) == ".webp" {
+		contentType = "image/webp"
+	}
 
-	// Upload to S3
-	_, err := s.s3Client.PutObject(&s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:        aws.String(s.bucketName),
 		Key:           aws.String(key),
 		Body:          bytes.NewReader(imageData),
-		ContentType:   aws.String("image/png"),
+		ContentType:   aws.String(contentType),
 		ContentLength: aws.Int64(int64(len(imageData))),
-		ACL:           aws.String("public-read"), // Make images publicly accessible
-	})
-	if err != nil {
+	}
+	if !s.private {
+		input.ACL = aws.String("public-read") // Make images publicly accessible
+	}
+
+	if _, err := s.s3Client.PutObject(input); err != nil {
 		return fmt.Errorf("failed to upload image to Supabase: %w", err)
 	}
 
 	return nil
 }
 
-// GetImageURL returns the public URL for an image stored in Supabase
-// URL format: {publicURL}/{date}/{index}.png
-func (s *SupabaseStorage) GetImageURL(date string, index int) string {
-	key := fmt.Sprintf("%s/%d.png", date, index)
+// ObjectURL returns the URL clients load a key from
+func (s *SupabaseStorage) ObjectURL(key string) string {
 	return fmt.Sprintf("%s/%s", s.publicURL, key)
 }
 
-// GetImagePath returns the S3 key path for an image (for reference)
-func (s *SupabaseStorage) GetImagePath(date string, index int) string {
-	return fmt.Sprintf("%s/%d.png", date, index)
+// SetURLBase changes the base of the URLs given out for new images, e.g. to the backend's
+// /api/images when images are proxied rather than loaded from the public bucket
+// Call once at startup, before the scheduler and handlers are running.
+func (s *SupabaseStorage) SetURLBase(base string) {
+	s.publicURL = strings.TrimSuffix(base, "/")
 }
 
-// GetImage retrieves an image from Supabase S3 (if needed for serving)
-func (s *SupabaseStorage) GetImage(date string, index int) ([]byte, error) {
-	key := fmt.Sprintf("%s/%d.png", date, index)
+// SetPrivate treats the bucket as private: objects are uploaded without a public-read ACL,
+// and new keys get a token derived from keySecret so that unreleased images can't be found
+// by guessing {date}/{index}.png. Keys are stored in image_path, so changing the secret only
+// affects images saved afterwards.
+// Call once at startup, before the scheduler and handlers are running.
+func (s *SupabaseStorage) SetPrivate(keySecret []byte) {
+	s.private = true
+	s.keySecret = keySecret
+}
+
+// Private reports whether the bucket is private
+func (s *SupabaseStorage) Private() bool {
+	return s.private
+}
+
+// privateKey returns name+ext, with a token for name appended when the bucket is private
+func (s *SupabaseStorage) privateKey(name, ext string) string {
+	if !s.private {
+		return name + ext
+	}
+	mac := hmac.New(sha256.New, s.keySecret)
+	mac.Write([]byte("image-key:" + name))
+	return name + "-" + hex.EncodeToString(mac.Sum(nil)[:16]) + ext
+}
+
+// SaveBankImage saves a reserve bank image to the bank folder
+// Path format: bank/{setID}/{index}.png, with a token for a private bucket
+func (s *SupabaseStorage) SaveBankImage(setID string, index int, imageData []byte) error {
+	return s.SaveObject(s.GetBankImagePath(setID, index), imageData)
+}
+
+// GetBankImageURL returns the public URL for a reserve bank image
+func (s *SupabaseStorage) GetBankImageURL(setID string, index int) string {
+	return s.ObjectURL(s.GetBankImagePath(setID, index))
+}
+
+// GetBankImagePath returns the S3 key for a reserve bank image
+func (s *SupabaseStorage) GetBankImagePath(setID string, index int) string {
+	return s.privateKey(fmt.Sprintf("bank/%s/%d", setID, index), ".png")
+}
+
+// ErrObjectNotFound is returned when a key doesn't exist in the bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// Object is an object read from the bucket
+type Object struct {
+	Data         []byte
+	ContentType  string
+	LastModified time.Time
+}
 
+// GetObject reads an object, returning ErrObjectNotFound if the key doesn't exist
+func (s *SupabaseStorage) GetObject(key string) (*Object, error) {
 	result, err := s.s3Client.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(s.bucketName),
 		Key:    aws.String(key),
 	})
 	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrObjectNotFound
+		}
 		return nil, fmt.Errorf("failed to get image from Supabase: %w", err)
 	}
 	defer result.Body.Close()
 
-	imageData, err := io.ReadAll(result.Body)
+	data, err := io.ReadAll(result.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image data: %w", err)
 	}
 
-	return imageData, nil
+	return &Object{
+		Data:         data,
+		ContentType:  aws.StringValue(result.ContentType),
+		LastModified: aws.TimeValue(result.LastModified),
+	}, nil
+}
+
+// PresignGetURL returns a URL that reads an object without credentials until ttl has passed
+func (s *SupabaseStorage) PresignGetURL(key string, ttl time.Duration) (string, error) {
+	req, _ := s.s3Client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	url, err := req.Presign(ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign image URL: %w", err)
+	}
+	return url, nil
+}
+
+// GetImage retrieves an image from Supabase S3 (if needed for serving)
+func (s *SupabaseStorage) GetImage(date string, index int) ([]byte, error) {
+	object, err := s.GetObject(s.GetImagePath(date, index))
+	if err != nil {
+		return nil, err
+	}
+	return object.Data, nil
 }
 
 // ImageExists checks if an image exists in Supabase S3
 func (s *SupabaseStorage) ImageExists(date string, index int) (bool, error) {
-	key := fmt.Sprintf("%s/%d.png", date, index)
+	key := s.GetImagePath(date, index)
 
 	_, err := s.s3Client.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(s.bucketName),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		// Check if it's a "not found" error
-		if aerr, ok := err.(awserr.Error); ok {
-			if aerr.Code() == "NotFound" || aerr.Code() == s3.ErrCodeNoSuchKey {
-				return false, nil
-			}
+		if isNotFound(err) {
+			return false, nil
 		}
 		return false, fmt.Errorf("failed to check image existence: %w", err)
 	}
@@ -121,3 +228,62 @@ func (s *SupabaseStorage) ImageExists(date string, index int) (bool, error) {
 	return true, nil
 }
 
+// isNotFound reports whether an S3 error means the key doesn't exist
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == "NotFound" || aerr.Code() == s3.ErrCodeNoSuchKey
+	}
+	return false
+}
+
+// ListObjects lists every object whose key starts with prefix
+func (s *SupabaseStorage) ListObjects(prefix string) ([]models.StoredImage, error) {
+	var objects []models.StoredImage
+	err := s.s3Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			objects = append(objects, models.StoredImage{
+				Path:         aws.StringValue(object.Key),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images in Supabase: %w", err)
+	}
+
+	return objects, nil
+}
+
+// DeleteObject deletes an object; deleting a key that doesn't exist isn't an error
+func (s *SupabaseStorage) DeleteObject(key string) error {
+	_, err := s.s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete image from Supabase: %w", err)
+	}
+
+	return nil
+}
+
+// MoveObject copies an object to a new key and deletes the original
+// The copy doesn't get the public-read ACL.
+func (s *SupabaseStorage) MoveObject(key, newKey string) error {
+	source := (&url.URL{Path: s.bucketName + "/" + key}).EscapedPath()
+	_, err := s.s3Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucketName),
+		Key:        aws.String(newKey),
+		CopySource: aws.String(source),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy image in Supabase: %w", err)
+	}
+
+	return s.DeleteObject(key)
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/s3fake"
+)
+
+const testBucket = "puzzle-images"
+
+// newTestStorage returns SupabaseStorage backed by a fake S3 server
+func newTestStorage(t *testing.T) (*SupabaseStorage, *s3fake.Server) {
+	t.Helper()
+	fake := s3fake.New()
+	t.Cleanup(fake.Close)
+
+	storage, err := NewSupabaseStorage(testBucket, "us-east-1", "access-key", "secret-key", fake.URL(), "https://cdn.example.com/"+testBucket)
+	if err != nil {
+		t.Fatalf("NewSupabaseStorage: %v", err)
+	}
+	return storage, fake
+}
+
+// testImage returns a PNG that survives processing: a white square of the given size on black
+func testImage(t *testing.T, size int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 800, 600))
+	for y := 200; y < 200+size; y++ {
+		for x := 300; x < 300+size; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSupabaseSaveImage(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	data := []byte("png data")
+
+	if err := storage.SaveImage("2025-01-15", 0, data); err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+
+	object, ok := fake.GetObject(testBucket, "2025-01-15/0.png")
+	if !ok {
+		t.Fatalf("object not stored; keys: %v", fake.Keys(testBucket))
+	}
+	if !bytes.Equal(object.Data, data) {
+		t.Errorf("stored %q, want %q", object.Data, data)
+	}
+	if object.ContentType != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", object.ContentType)
+	}
+	if object.ACL != "public-read" {
+		t.Errorf("ACL = %q, want public-read", object.ACL)
+	}
+
+	if got := storage.GetImageURL("2025-01-15", 0); got != "https://cdn.example.com/"+testBucket+"/2025-01-15/0.png" {
+		t.Errorf("GetImageURL = %q", got)
+	}
+}
+
+func TestSupabaseGetImage(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	data := []byte("png data")
+	if err := storage.SaveImage("2025-01-15", 1, data); err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+
+	got, err := storage.GetImage("2025-01-15", 1)
+	if err != nil {
+		t.Fatalf("GetImage: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("GetImage = %q, want %q", got, data)
+	}
+
+	if _, err := storage.GetImage("2025-01-15", 2); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetImage of a missing image: err = %v, want ErrObjectNotFound", err)
+	}
+
+	object, err := storage.GetObject("2025-01-15/1.png")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if object.ContentType != "image/png" || object.LastModified.IsZero() {
+		t.Errorf("GetObject = %q, %v; want image/png and a modification time", object.ContentType, object.LastModified)
+	}
+}
+
+func TestSupabaseImageExists(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	if err := storage.SaveImage("2025-01-15", 0, []byte("png data")); err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+
+	for _, tt := range []struct {
+		index int
+		want  bool
+	}{
+		{0, true},
+		{1, false},
+	} {
+		got, err := storage.ImageExists("2025-01-15", tt.index)
+		if err != nil {
+			t.Fatalf("ImageExists(%d): %v", tt.index, err)
+		}
+		if got != tt.want {
+			t.Errorf("ImageExists(%d) = %t, want %t", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestSupabasePrivateBucket(t *testing.T) {
+	storage, fake := newTestStorage(t)
+	storage.SetPrivate([]byte("key secret"))
+
+	if err := storage.SaveImage("2025-01-15", 0, []byte("png data")); err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+
+	key := storage.GetImagePath("2025-01-15", 0)
+	if !strings.HasPrefix(key, "2025-01-15/0-") || key == "2025-01-15/0.png" {
+		t.Fatalf("private key %q has no token", key)
+	}
+	object, ok := fake.GetObject(testBucket, key)
+	if !ok {
+		t.Fatalf("object not stored under %q; keys: %v", key, fake.Keys(testBucket))
+	}
+	if object.ACL != "" {
+		t.Errorf("ACL = %q, want none", object.ACL)
+	}
+
+	if exists, err := storage.ImageExists("2025-01-15", 0); err != nil || !exists {
+		t.Errorf("ImageExists = %t, %v; want true", exists, err)
+	}
+
+	// Anonymous reads are refused, presigned ones aren't
+	resp, err := http.Get(fake.URL() + "/" + testBucket + "/" + key)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("anonymous GET = %d, want 403", resp.StatusCode)
+	}
+
+	url, err := storage.PresignGetURL(key, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignGetURL: %v", err)
+	}
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("GET presigned: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("presigned GET = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestSupabaseListDeleteMove(t *testing.T) {
+	storage, fake := newTestStorage(t)
+
+	// More than one page of results
+	for i := 0; i < 1005; i++ {
+		fake.PutObject(testBucket, fmt.Sprintf("2025-01-15/%04d.png", i), []byte("png data"), "image/png", "")
+	}
+	fake.PutObject(testBucket, "quarantine/2025-01-14/0.png", []byte("old"), "image/png", "")
+
+	objects, err := storage.ListObjects("2025-01-15/")
+	if err != nil {
+		t.Fatalf("ListObjects: %v", err)
+	}
+	if len(objects) != 1005 {
+		t.Fatalf("ListObjects returned %d objects, want 1005", len(objects))
+	}
+	if objects[0].Path != "2025-01-15/0000.png" || objects[0].Size != 8 || objects[0].LastModified.IsZero() {
+		t.Errorf("first object = %+v", objects[0])
+	}
+
+	if err := storage.MoveObject("2025-01-15/0000.png", "quarantine/2025-01-15/0000.png"); err != nil {
+		t.Fatalf("MoveObject: %v", err)
+	}
+	if _, ok := fake.GetObject(testBucket, "2025-01-15/0000.png"); ok {
+		t.Error("MoveObject left the original in place")
+	}
+	if moved, ok := fake.GetObject(testBucket, "quarantine/2025-01-15/0000.png"); !ok || string(moved.Data) != "png data" {
+		t.Error("MoveObject didn't copy the object")
+	}
+
+	if err := storage.DeleteObject("2025-01-15/0001.png"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	if err := storage.DeleteObject("2025-01-15/0001.png"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if _, err := storage.GetObject("2025-01-15/0001.png"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObject after delete: err = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestStoreSaveImageToSupabase(t *testing.T) {
+	fake := s3fake.New()
+	defer fake.Close()
+
+	s, err := NewStoreWithSupabase(nil, testBucket, "us-east-1", "access-key", "secret-key", fake.URL(), "https://cdn.example.com/"+testBucket)
+	if err != nil {
+		t.Fatalf("NewStoreWithSupabase: %v", err)
+	}
+
+	imageURL, imagePath, meta, err := s.SaveImage(testImage(t, 200))
+	if err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+
+	hash, ok := ContentHash(imagePath)
+	if !ok || imagePath != "sha256/"+hash+".png" {
+		t.Fatalf("image path %q isn't content-addressed", imagePath)
+	}
+	if meta.ImageSHA256 != hash {
+		t.Errorf("ImageSHA256 = %q, want %q", meta.ImageSHA256, hash)
+	}
+	if imageURL != "https://cdn.example.com/"+testBucket+"/"+imagePath {
+		t.Errorf("image URL = %q", imageURL)
+	}
+
+	object, ok := fake.GetObject(testBucket, imagePath)
+	if !ok {
+		t.Fatalf("image not stored; keys: %v", fake.Keys(testBucket))
+	}
+	if int64(len(object.Data)) != meta.ImageSize {
+		t.Errorf("ImageSize = %d, stored %d bytes", meta.ImageSize, len(object.Data))
+	}
+	if err := VerifyImage(imagePath, object.Data); err != nil {
+		t.Errorf("VerifyImage: %v", err)
+	}
+	if len(meta.ImageVariants) == 0 {
+		t.Error("no image variants recorded")
+	}
+	for _, variant := range meta.ImageVariants {
+		for _, format := range []string{"png", "webp"} {
+			key := models.ImageVariantPath(imagePath, variant.Name, format)
+			if _, ok := fake.GetObject(testBucket, key); !ok {
+				t.Errorf("variant %s not stored", key)
+			}
+		}
+	}
+
+	// Saving the same image again lands on the same key; a different image doesn't
+	_, samePath, _, err := s.SaveImage(testImage(t, 200))
+	if err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+	if samePath != imagePath {
+		t.Errorf("same image saved to %q and %q", imagePath, samePath)
+	}
+	_, otherPath, _, err := s.SaveImage(testImage(t, 100))
+	if err != nil {
+		t.Fatalf("SaveImage: %v", err)
+	}
+	if otherPath == imagePath {
+		t.Error("different images saved to the same key")
+	}
+
+	// Reads check the data against its hash
+	if _, err := s.readImageFile(imagePath); err != nil {
+		t.Errorf("readImageFile: %v", err)
+	}
+	fake.PutObject(testBucket, imagePath, []byte("corrupted"), "image/png", "public-read")
+	if _, err := s.readImageFile(imagePath); !errors.Is(err, ErrImageCorrupt) {
+		t.Errorf("readImageFile of a corrupted image: err = %v, want ErrImageCorrupt", err)
+	}
+}
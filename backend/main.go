@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -12,9 +14,14 @@ import (
 	"github.com/gorilla/mux"
 
 	"backend/internal/ai"
+	"backend/internal/auth"
 	"backend/internal/config"
 	"backend/internal/database"
 	"backend/internal/handlers"
+	"backend/internal/imageproc"
+	"backend/internal/models"
+	"backend/internal/ratelimit"
+	"backend/internal/release"
 	"backend/internal/scheduler"
 	"backend/internal/store"
 	"fmt"
@@ -38,6 +45,13 @@ func main() {
 	defer db.Close()
 	log.Println("Connected to PostgreSQL database")
 
+	// The publication timezone defines "today" for generation, releases and the API
+	publicationLocation, err := time.LoadLocation(cfg.PublicationTimezone)
+	if err != nil {
+		log.Fatalf("Invalid PUBLICATION_TIMEZONE %q: %v", cfg.PublicationTimezone, err)
+	}
+	store.SetPublicationLocation(publicationLocation)
+
 	// Initialize store - use Supabase if configured, otherwise use file system
 	var storeInstance *store.Store
 	if cfg.SupabaseS3Bucket != "" && cfg.SupabaseS3AccessKey != "" && cfg.SupabaseS3SecretKey != "" {
@@ -62,6 +76,39 @@ func main() {
 		}
 	}
 
+	// Images are normalized to white on black at a fixed size before they're stored
+	if cfg.ImageThreshold < 0 || cfg.ImageThreshold > 255 {
+		log.Fatalf("Invalid IMAGE_THRESHOLD %d: must be 0-255", cfg.ImageThreshold)
+	}
+	imageOptions := imageproc.DefaultOptions()
+	imageOptions.Width = cfg.ImageWidth
+	imageOptions.Height = cfg.ImageHeight
+	imageOptions.Threshold = uint8(cfg.ImageThreshold)
+	storeInstance.SetImageOptions(imageOptions)
+
+	// Unless Supabase images are linked directly, new image URLs point at /api/images so the bucket can change
+	supabaseStorage := storeInstance.SupabaseStorage()
+	switch cfg.SupabaseImageMode {
+	case handlers.SupabaseModePublic:
+		// Image URLs point straight at the public bucket
+	case handlers.SupabaseModeProxy, handlers.SupabaseModeRedirect:
+		if supabaseStorage != nil {
+			supabaseStorage.SetURLBase("/api/images")
+		}
+	default:
+		log.Fatalf("Invalid SUPABASE_IMAGE_MODE %q: must be public, proxy or redirect", cfg.SupabaseImageMode)
+	}
+	if cfg.SupabasePresignTTL <= 0 || cfg.SupabasePresignTTL > 7*24*60 {
+		log.Fatalf("Invalid SUPABASE_PRESIGN_TTL_MINUTES %d: must be between 1 and 10080", cfg.SupabasePresignTTL)
+	}
+	// A private bucket can only be read through the backend, which holds images back until they're released
+	if cfg.SupabasePrivate && supabaseStorage != nil {
+		if cfg.SupabaseImageMode == handlers.SupabaseModePublic {
+			log.Fatalf("SUPABASE_PRIVATE_BUCKET needs SUPABASE_IMAGE_MODE proxy or redirect")
+		}
+		supabaseStorage.SetPrivate([]byte(cfg.SupabaseS3SecretKey))
+	}
+
 	// Initialize AI generator - always use real generator with Claude API and Replicate
 	var aiGenerator ai.AIGenerator
 	if cfg.ClaudeAPIKey == "" || cfg.ReplicateAPIKey == "" {
@@ -72,8 +119,61 @@ func main() {
 	log.Println("Using real AI generator with Claude API and Replicate")
 	aiGenerator = ai.NewRealAIGenerator(cfg.ClaudeAPIKey, cfg.ReplicateAPIKey, cfg.Environment)
 
+	// Initialize release policy
+	releasePolicy, err := release.NewPolicy(storeInstance, publicationLocation, cfg.ReleaseHour)
+	if err != nil {
+		log.Fatalf("Failed to initialize release policy: %v", err)
+	}
+
+	// The image GC job never runs as a dry run, and a grace period protects images of generations in progress
+	if cfg.ImageGCAction != models.ImageGCDelete && cfg.ImageGCAction != models.ImageGCQuarantine {
+		log.Fatalf("Invalid IMAGE_GC_ACTION %q: must be delete or quarantine", cfg.ImageGCAction)
+	}
+	if cfg.ImageGCGraceHours < 1 {
+		log.Fatalf("Invalid IMAGE_GC_GRACE_HOURS %d: must be at least 1", cfg.ImageGCGraceHours)
+	}
+
 	// Initialize scheduler
-	sched := scheduler.NewScheduler(storeInstance, aiGenerator, cfg.BatchJobHour, cfg.BatchJobMinute)
+	sched, err := scheduler.NewScheduler(storeInstance, aiGenerator, scheduler.Config{
+		Generate:              scheduler.JobConfig{Schedule: cfg.GenerateJobSchedule, Enabled: cfg.GenerateJobEnabled},
+		Cleanup:               scheduler.JobConfig{Schedule: cfg.CleanupJobSchedule, Enabled: cfg.CleanupJobEnabled},
+		Stats:                 scheduler.JobConfig{Schedule: cfg.StatsJobSchedule, Enabled: cfg.StatsJobEnabled},
+		Reminders:             scheduler.JobConfig{Schedule: cfg.RemindersJobSchedule, Enabled: cfg.RemindersJobEnabled},
+		Fallback:              scheduler.JobConfig{Schedule: cfg.FallbackJobSchedule, Enabled: cfg.FallbackJobEnabled},
+		BankTopup:             scheduler.JobConfig{Schedule: cfg.BankTopupJobSchedule, Enabled: cfg.BankTopupJobEnabled},
+		Publish:               scheduler.JobConfig{Schedule: cfg.PublishJobSchedule, Enabled: cfg.PublishJobEnabled},
+		ImageGC:               scheduler.JobConfig{Schedule: cfg.ImageGCJobSchedule, Enabled: cfg.ImageGCJobEnabled},
+		BufferDays:            cfg.GenerationBufferDays,
+		ProgressRetentionDays: cfg.ProgressRetentionDays,
+		InstanceID:            cfg.InstanceID,
+		BackfillConcurrency:   cfg.BackfillConcurrency,
+		BackfillBudget:        cfg.BackfillBudget,
+		BankTargetSets:        cfg.BankTargetSets,
+		BankAutoApprove:       cfg.BankAutoApprove,
+		ReviewRequired:        cfg.ReviewRequired,
+		ImageGCGraceHours:     cfg.ImageGCGraceHours,
+		ImageGCAction:         cfg.ImageGCAction,
+		ReleasePolicy:         releasePolicy,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize scheduler: %v", err)
+	}
+
+	// "backend backfill --from ... --to ..." runs a backfill and exits without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		os.Exit(runBackfillCommand(sched, os.Args[2:]))
+	}
+
+	// "backend create-token --name ... --role ..." creates an API token and exits
+	if len(os.Args) > 1 && os.Args[1] == "create-token" {
+		os.Exit(runCreateTokenCommand(storeInstance, os.Args[2:]))
+	}
+
+	// "backend migrate-images --to supabase" moves images between storage backends and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate-images" {
+		os.Exit(runMigrateImagesCommand(db, storeInstance, cfg, os.Args[2:]))
+	}
+
 	sched.Start()
 
 	// Setup graceful shutdown
@@ -87,36 +187,120 @@ func main() {
 		os.Exit(0)
 	}()
 
+	// Initialize API authentication
+	authenticator := auth.NewAuthenticator(storeInstance, cfg.AdminAPIKey)
+
+	// Initialize rate limiting
+	verifyRule, err := ratelimit.ParseRule(cfg.RateLimitVerify)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_VERIFY: %v", err)
+	}
+	verifyIPRule, err := ratelimit.ParseRule(cfg.RateLimitVerifyIP)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_VERIFY_IP: %v", err)
+	}
+	triggerRule, err := ratelimit.ParseRule(cfg.RateLimitTrigger)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_TRIGGER: %v", err)
+	}
+	var limiter ratelimit.Limiter
+	switch cfg.RateLimitBackend {
+	case "memory":
+		limiter = ratelimit.NewMemoryLimiter()
+	case "postgres":
+		limiter = ratelimit.NewPostgresLimiter(storeInstance)
+	default:
+		log.Fatalf("Invalid RATE_LIMIT_BACKEND %q: must be memory or postgres", cfg.RateLimitBackend)
+	}
+	log.Printf("Rate limits (%s): verify %s per player and %s per IP, trigger %s per token", cfg.RateLimitBackend, verifyRule, verifyIPRule, triggerRule)
+	// Player IDs are chosen by clients, so the per-IP limit is what a new ID can't get around
+	limitVerifyIP := ratelimit.Middleware(limiter, "verify-ip", verifyIPRule, ratelimit.ByIP(cfg.TrustProxyHeaders))
+	limitVerifyPlayer := ratelimit.Middleware(limiter, "verify", verifyRule, ratelimit.ByPlayer(cfg.TrustProxyHeaders))
+	limitVerify := func(next http.Handler) http.Handler {
+		return limitVerifyIP(limitVerifyPlayer(next))
+	}
+	limitTrigger := ratelimit.Middleware(limiter, "trigger", triggerRule, func(r *http.Request) string {
+		return "token:" + authenticator.Actor(r)
+	})
+
 	// Initialize handlers
-	puzzleHandler := handlers.NewPuzzleHandler(storeInstance, sched)
+	puzzleHandler := handlers.NewPuzzleHandler(storeInstance, sched, releasePolicy, authenticator, cfg.MaxAttemptsPerPuzzle)
+	archiveHandler := handlers.NewArchiveHandler(storeInstance, releasePolicy)
+	healthHandler := handlers.NewHealthHandler(db, sched)
+	adminHandler := handlers.NewAdminHandler(storeInstance, releasePolicy, sched, authenticator)
 	var imageHandler *handlers.ImageHandler
-	if cfg.SupabaseS3Bucket != "" && cfg.SupabaseS3PublicURL != "" {
+	switch {
+	case supabaseStorage != nil && cfg.SupabaseImageMode == handlers.SupabaseModeProxy:
+		imageHandler = handlers.NewImageProxyHandler(supabaseStorage, int64(cfg.ImageProxyCacheMB)<<20)
+	case supabaseStorage != nil && cfg.SupabaseImageMode == handlers.SupabaseModeRedirect:
+		imageHandler = handlers.NewImageRedirectHandler(supabaseStorage, time.Duration(cfg.SupabasePresignTTL)*time.Minute)
+	case cfg.SupabaseS3Bucket != "" && cfg.SupabaseS3PublicURL != "":
 		imageHandler = handlers.NewImageHandlerWithSupabase(cfg.SupabaseS3PublicURL)
-	} else {
+	default:
 		imageHandler = handlers.NewImageHandler(cfg.ImagesPath)
 	}
+	if supabaseStorage != nil && supabaseStorage.Private() {
+		imageHandler.RequireRelease(storeInstance, releasePolicy, authenticator)
+	}
 
 	// Setup router
 	r := mux.NewRouter()
 
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/puzzles/today", puzzleHandler.GetTodayPuzzlesHandler).Methods("GET")
 	api.HandleFunc("/puzzles/{date}", puzzleHandler.GetPuzzlesHandler).Methods("GET")
-	api.HandleFunc("/puzzles/verify", puzzleHandler.VerifyAnswerHandler).Methods("POST")
-	api.HandleFunc("/puzzles/trigger", puzzleHandler.TriggerJobHandler).Methods("POST")
-	api.HandleFunc("/images/{filename}", imageHandler.ServeImage).Methods("GET")
+	api.Handle("/puzzles/verify", limitVerify(http.HandlerFunc(puzzleHandler.VerifyAnswerHandler))).Methods("POST")
+	api.HandleFunc("/puzzles/{id}/giveup", puzzleHandler.GiveUpHandler).Methods("POST")
+	api.HandleFunc("/puzzles/{id}/solution", puzzleHandler.SolutionHandler).Methods("GET")
+	api.Handle("/puzzles/trigger", authenticator.RequireRole(auth.RoleEditor)(limitTrigger(http.HandlerFunc(puzzleHandler.TriggerJobHandler)))).Methods("POST")
+	api.HandleFunc("/archive", archiveHandler.GetArchiveHandler).Methods("GET")
+	api.HandleFunc("/images/{filename:.+}", imageHandler.ServeImage).Methods("GET")
+
+	// Admin routes, split by the minimum role each needs
+	viewer := api.PathPrefix("/admin").Subrouter()
+	viewer.Use(authenticator.RequireRole(auth.RoleViewer))
+	viewer.HandleFunc("/releases/{date}", adminHandler.GetReleaseHandler).Methods("GET")
+	viewer.HandleFunc("/schedule", adminHandler.GetScheduleHandler).Methods("GET")
+	viewer.HandleFunc("/backfill/{id}", adminHandler.GetBackfillHandler).Methods("GET")
+	viewer.HandleFunc("/puzzles", adminHandler.ListPuzzlesHandler).Methods("GET")
+	viewer.HandleFunc("/puzzles/pending", adminHandler.ListPendingPuzzlesHandler).Methods("GET")
+	viewer.HandleFunc("/puzzles/{id}", adminHandler.GetPuzzleHandler).Methods("GET")
+	viewer.HandleFunc("/puzzles/{id}/audit", adminHandler.GetPuzzleAuditHandler).Methods("GET")
+	viewer.HandleFunc("/puzzles/{id}/images", adminHandler.ListImageVersionsHandler).Methods("GET")
+	viewer.HandleFunc("/bank", adminHandler.ListBankHandler).Methods("GET")
+	viewer.HandleFunc("/images/orphans", adminHandler.GetOrphanedImagesHandler).Methods("GET")
+
+	editor := api.PathPrefix("/admin").Subrouter()
+	editor.Use(authenticator.RequireRole(auth.RoleEditor))
+	editor.HandleFunc("/releases/{date}", adminHandler.SetReleaseHandler).Methods("PUT")
+	editor.HandleFunc("/releases/{date}", adminHandler.DeleteReleaseHandler).Methods("DELETE")
+	editor.HandleFunc("/puzzles", adminHandler.CreatePuzzleHandler).Methods("POST")
+	editor.HandleFunc("/puzzles/reorder", adminHandler.ReorderPuzzlesHandler).Methods("POST")
+	editor.HandleFunc("/puzzles/{id}", adminHandler.UpdatePuzzleHandler).Methods("PATCH")
+	editor.HandleFunc("/puzzles/{id}", adminHandler.DeletePuzzleHandler).Methods("DELETE")
+	editor.HandleFunc("/puzzles/{id}/image", adminHandler.ReplaceImageHandler).Methods("PUT")
+	editor.HandleFunc("/puzzles/{id}/image/rollback", adminHandler.RollbackImageHandler).Methods("POST")
+	editor.HandleFunc("/puzzles/{id}/approve", adminHandler.ApprovePuzzleHandler).Methods("POST")
+	editor.HandleFunc("/puzzles/{id}/reject", adminHandler.RejectPuzzleHandler).Methods("POST")
+	editor.HandleFunc("/bank/{setId}/approve", adminHandler.ApproveBankSetHandler).Methods("POST")
+
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authenticator.RequireRole(auth.RoleAdmin))
+	admin.HandleFunc("/backfill", adminHandler.StartBackfillHandler).Methods("POST")
+	admin.HandleFunc("/tokens", adminHandler.ListTokensHandler).Methods("GET")
+	admin.HandleFunc("/tokens", adminHandler.CreateTokenHandler).Methods("POST")
+	admin.HandleFunc("/tokens/{id}", adminHandler.RevokeTokenHandler).Methods("DELETE")
 
 	// Health check endpoint
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
+	r.HandleFunc("/health", healthHandler.ServeHealth).Methods("GET")
 
 	// CORS middleware
 	corsHandler := gorillaHandlers.CORS(
 		gorillaHandlers.AllowedOrigins(cfg.AllowedOrigins),
-		gorillaHandlers.AllowedMethods([]string{"GET", "POST", "OPTIONS"}),
-		gorillaHandlers.AllowedHeaders([]string{"Content-Type"}),
+		gorillaHandlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		gorillaHandlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-API-Key", "X-Player-ID", "X-Player-Timezone"}),
+		gorillaHandlers.ExposedHeaders([]string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"}),
 	)(r)
 
 	// Create server
@@ -130,14 +314,185 @@ func main() {
 
 	log.Printf("Server starting on port %s", cfg.Port)
 	log.Printf("API endpoints:")
+	log.Printf("  GET  /api/puzzles/today - Get today's puzzles in the player's timezone")
 	log.Printf("  GET  /api/puzzles/{date} - Get puzzles for a date")
-	log.Printf("  POST /api/puzzles/verify - Verify an answer")
-	log.Printf("  POST /api/puzzles/trigger - Trigger puzzle generation for today")
+	log.Printf("  POST /api/puzzles/verify - Verify an answer (rate limited)")
+	log.Printf("  POST /api/puzzles/{id}/giveup - Give up on a puzzle and reveal the answer")
+	log.Printf("  GET  /api/puzzles/{id}/solution - Get a finished puzzle's answer and explanation")
+	log.Printf("  POST /api/puzzles/trigger - Trigger puzzle generation for today (editor, rate limited)")
+	log.Printf("  GET  /api/archive - Browse past puzzle days")
 	log.Printf("  GET  /api/images/{filename} - Get puzzle image")
-	log.Printf("Batch job scheduled to run daily at %02d:%02d", cfg.BatchJobHour, cfg.BatchJobMinute)
+	log.Printf("  GET/PUT/DELETE /api/admin/releases/{date} - Manage a date's release time (viewer to read, editor to change)")
+	log.Printf("  GET  /api/admin/schedule - List scheduled jobs and next run times (viewer)")
+	log.Printf("  POST /api/admin/backfill - Generate puzzles for a date range (admin)")
+	log.Printf("  GET  /api/admin/backfill/{id} - Get a backfill's per-date results (viewer)")
+	log.Printf("  GET  /api/admin/puzzles?date= - List a day's puzzles in any status (viewer)")
+	log.Printf("  POST /api/admin/puzzles - Upload a hand-made draft puzzle (editor)")
+	log.Printf("  GET/PATCH/DELETE /api/admin/puzzles/{id} - View, edit or delete a puzzle (viewer to read, editor to change)")
+	log.Printf("  PUT  /api/admin/puzzles/{id}/image - Replace a puzzle's image (editor)")
+	log.Printf("  POST /api/admin/puzzles/reorder - Reorder a day's puzzles (editor)")
+	log.Printf("  GET  /api/admin/puzzles/{id}/audit - List a puzzle's change history (viewer)")
+	log.Printf("  GET  /api/admin/puzzles/pending - List puzzles awaiting review (viewer)")
+	log.Printf("  POST /api/admin/puzzles/{id}/approve - Approve a puzzle (editor)")
+	log.Printf("  POST /api/admin/puzzles/{id}/reject - Reject a puzzle (editor)")
+	log.Printf("  GET  /api/admin/bank - List reserve puzzle sets (viewer)")
+	log.Printf("  POST /api/admin/bank/{setId}/approve - Approve a reserve set for promotion (editor)")
+	log.Printf("  GET/POST /api/admin/tokens - List or create API tokens (admin)")
+	log.Printf("  DELETE /api/admin/tokens/{id} - Revoke an API token (admin)")
+	log.Printf("Generate job schedule: %q (%s)", cfg.GenerateJobSchedule, cfg.PublicationTimezone)
 
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
 
+// runBackfillCommand runs the backfill subcommand and returns the process exit code
+// Usage: backend backfill --from 2024-01-01 --to 2024-01-31 [--force] [--concurrency 2]
+func runBackfillCommand(sched *scheduler.Scheduler, args []string) int {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := flags.String("from", "", "first date to generate (YYYY-MM-DD)")
+	to := flags.String("to", "", "last date to generate (YYYY-MM-DD, defaults to --from)")
+	force := flags.Bool("force", false, "regenerate dates that already have puzzles")
+	concurrency := flags.Int("concurrency", 0, "dates to generate at once (defaults to BACKFILL_CONCURRENCY)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *to == "" {
+		*to = *from
+	}
+
+	// Ctrl-C stops new dates from starting; dates already generating are allowed to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := sched.Backfill(ctx, models.BackfillRequest{
+		From:        *from,
+		To:          *to,
+		Force:       *force,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 2
+	}
+
+	for _, result := range report.Results {
+		if result.Error != "" {
+			fmt.Printf("%s  %-12s %s\n", result.Date, result.Status, result.Error)
+		} else {
+			fmt.Printf("%s  %s\n", result.Date, result.Status)
+		}
+	}
+	fmt.Printf("\n%d generated, %d skipped, %d in progress elsewhere, %d over budget, %d cancelled, %d failed\n",
+		report.Summary[models.BackfillGenerated],
+		report.Summary[models.BackfillSkipped],
+		report.Summary[models.BackfillInProgress],
+		report.Summary[models.BackfillOverBudget],
+		report.Summary[models.BackfillCancelled],
+		report.Summary[models.BackfillFailed],
+	)
+
+	if report.Summary[models.BackfillFailed] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runMigrateImagesCommand runs the migrate-images subcommand and returns the process exit code
+// Usage: backend migrate-images --to supabase|filesystem [--dry-run]
+// Supabase must be configured either way; the other side is IMAGES_PATH.
+func runMigrateImagesCommand(db *database.DB, configured *store.Store, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate-images", flag.ContinueOnError)
+	to := flags.String("to", "", "backend to move images to: supabase or filesystem")
+	dryRun := flags.Bool("dry-run", false, "list the images that would move without copying anything")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *to != "supabase" && *to != "filesystem" {
+		fmt.Fprintln(os.Stderr, "migrate-images: --to must be supabase or filesystem")
+		return 2
+	}
+	if configured.SupabaseStorage() == nil {
+		fmt.Fprintln(os.Stderr, "migrate-images: Supabase storage isn't configured (SUPABASE_S3_BUCKET, SUPABASE_S3_ACCESS_KEY, SUPABASE_S3_SECRET_KEY)")
+		return 2
+	}
+
+	fileStore, err := store.NewStore(db, cfg.ImagesPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-images: %v\n", err)
+		return 1
+	}
+	src, dst := fileStore, configured
+	if *to == "filesystem" {
+		src, dst = configured, fileStore
+	}
+
+	// Ctrl-C stops before the next image; the one being copied is finished first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, err := store.MigrateImages(ctx, src, dst, *dryRun, func(result models.ImageMigrationResult) {
+		switch {
+		case result.Error != "":
+			fmt.Printf("%-16s %s: %s\n", result.Status, result.OldPath, result.Error)
+		case result.NewPath != "":
+			fmt.Printf("%-16s %s -> %s\n", result.Status, result.OldPath, result.NewPath)
+		default:
+			fmt.Printf("%-16s %s\n", result.Status, result.OldPath)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-images: %v\n", err)
+		return 1
+	}
+
+	summary := make(map[string]int)
+	var files int
+	var bytes int64
+	for _, result := range results {
+		summary[result.Status]++
+		files += result.Files
+		bytes += result.Bytes
+	}
+	fmt.Printf("\n%d migrated (%d files, %d bytes), %d already migrated, %d would migrate, %d missing, %d cancelled, %d failed\n",
+		summary[models.ImageMigrated], files, bytes,
+		summary[models.ImageAlreadyMigrated],
+		summary[models.ImageWouldMigrate],
+		summary[models.ImageMissing],
+		summary[models.ImageMigrationCancelled],
+		summary[models.ImageMigrationFailed],
+	)
+
+	if summary[models.ImageMigrationFailed] > 0 || summary[models.ImageMigrationCancelled] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runCreateTokenCommand runs the create-token subcommand and returns the process exit code
+// Usage: backend create-token --name ci --role editor
+func runCreateTokenCommand(s *store.Store, args []string) int {
+	flags := flag.NewFlagSet("create-token", flag.ContinueOnError)
+	name := flags.String("name", "", "who or what the token is for")
+	role := flags.String("role", auth.RoleViewer, "viewer, editor or admin")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" || !auth.IsValidRole(*role) {
+		fmt.Fprintln(os.Stderr, "create-token: --name is required and --role must be viewer, editor or admin")
+		return 2
+	}
+
+	token, tokenHash, err := auth.GenerateToken()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create-token: %v\n", err)
+		return 1
+	}
+	if _, err := s.CreateAPIToken(*name, *role, tokenHash, "cli"); err != nil {
+		fmt.Fprintf(os.Stderr, "create-token: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Created %s token %q. It won't be shown again:\n%s\n", *role, *name, token)
+	return 0
+}
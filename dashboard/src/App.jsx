@@ -5,6 +5,16 @@ import './App.css'
 // Defaults to http://localhost:8080 for local development
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080'
 
+// Get a stable anonymous player ID for this browser (used for attempt limits)
+const getPlayerId = () => {
+  let playerId = localStorage.getItem('rebus_player_id')
+  if (!playerId) {
+    playerId = crypto.randomUUID()
+    localStorage.setItem('rebus_player_id', playerId)
+  }
+  return playerId
+}
+
 function App() {
   const [puzzles, setPuzzles] = useState([])
   const [currentPuzzleIndex, setCurrentPuzzleIndex] = useState(0)
@@ -12,11 +22,14 @@ function App() {
   const [isLoading, setIsLoading] = useState(true)
   const [error, setError] = useState(null)
   const [solvedPuzzles, setSolvedPuzzles] = useState([]) // Array of booleans: [true, false, true, ...]
+  const [answers, setAnswers] = useState({}) // Revealed answers keyed by puzzle ID
 
-  // Get today's date in YYYY-MM-DD format
+  // Get today's date in YYYY-MM-DD format (player's local day, matching /api/puzzles/today)
   const getTodayDate = () => {
     const today = new Date()
-    return today.toISOString().split('T')[0]
+    const month = String(today.getMonth() + 1).padStart(2, '0')
+    const day = String(today.getDate()).padStart(2, '0')
+    return `${today.getFullYear()}-${month}-${day}`
   }
 
   // Load solved puzzles from browser cache (localStorage)
@@ -50,8 +63,9 @@ function App() {
       setIsLoading(true)
       setError(null)
       try {
-        const today = getTodayDate()
-        const response = await fetch(`${API_BASE_URL}/api/puzzles/${today}`)
+        const response = await fetch(`${API_BASE_URL}/api/puzzles/today`, {
+          headers: { 'X-Player-Timezone': Intl.DateTimeFormat().resolvedOptions().timeZone },
+        })
         
         if (!response.ok) {
           throw new Error('Failed to fetch puzzles')
@@ -77,6 +91,23 @@ function App() {
     fetchPuzzles()
   }, [])
 
+  // Fetch revealed answers for puzzles solved in an earlier visit
+  useEffect(() => {
+    puzzles.forEach(async (puzzle, index) => {
+      if (solvedPuzzles[index] !== true || answers[puzzle.id]) return
+      try {
+        const response = await fetch(`${API_BASE_URL}/api/puzzles/${puzzle.id}/solution`, {
+          headers: { 'X-Player-ID': getPlayerId() },
+        })
+        if (!response.ok) return
+        const data = await response.json()
+        setAnswers(prev => ({ ...prev, [puzzle.id]: data.answer }))
+      } catch (err) {
+        console.error('Error fetching solution:', err)
+      }
+    })
+  }, [puzzles, solvedPuzzles])
+
   // Check if a puzzle is solved
   const isPuzzleSolved = (index) => {
     return solvedPuzzles[index] === true
@@ -120,14 +151,20 @@ function App() {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          'X-Player-ID': getPlayerId(),
         },
         body: JSON.stringify({
           puzzleId: currentPuzzle.id,
+          playerId: getPlayerId(),
           answer: userAnswer.trim().toLowerCase(),
         }),
       })
 
       const data = await response.json()
+
+      if (data.answer) {
+        setAnswers(prev => ({ ...prev, [currentPuzzle.id]: data.answer }))
+      }
       
       if (data.correct) {
         // Save the solved puzzle
@@ -273,7 +310,9 @@ function App() {
           ) : (
             <div className="solved-message">
               <p>🎉 You solved this puzzle!</p>
-              <p className="answer-reveal">Answer: {currentPuzzle.answer}</p>
+              {answers[currentPuzzle.id] && (
+                <p className="answer-reveal">Answer: {answers[currentPuzzle.id]}</p>
+              )}
             </div>
           )}
         </div>